@@ -0,0 +1,237 @@
+package debuginfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+var binaryMagic = [2]byte{0xde, 0xbf}
+
+// Record types in the binary format written by Inform 6.32 and earlier
+const (
+	dbr_EOF        = 0
+	dbr_File       = 1
+	dbr_Class      = 2
+	dbr_Object     = 3
+	dbr_Global     = 4
+	dbr_Attribute  = 5
+	dbr_Property   = 6
+	dbr_FakeAction = 7
+	dbr_Action     = 8
+	dbr_Header     = 9
+	dbr_LineRef    = 10
+	dbr_Routine    = 11
+	dbr_Array      = 12
+	dbr_Map        = 13
+	dbr_RoutineEnd = 14
+)
+
+const headerLength = 64
+
+// binaryReader reads the fields of binary records. The first error stops all further reads and
+// is kept until the end.
+type binaryReader struct {
+	reader *bufio.Reader
+	err    error
+}
+
+func (b *binaryReader) byte() int {
+	if b.err != nil {
+		return 0
+	}
+	value, err := b.reader.ReadByte()
+	b.fail(err)
+	return int(value)
+}
+
+func (b *binaryReader) word() int {
+	return b.byte()<<8 | b.byte()
+}
+
+// address reads a 3 byte address
+func (b *binaryReader) address() int {
+	return b.byte()<<16 | b.word()
+}
+
+func (b *binaryReader) string() string {
+	if b.err != nil {
+		return ""
+	}
+	value, err := b.reader.ReadString(0)
+	b.fail(err)
+	return strings.TrimSuffix(value, "\x00")
+}
+
+// line reads a source position, which is a file number, line number and character position
+func (b *binaryReader) line() (int, int) {
+	file, line := b.byte(), b.word()
+	b.byte()
+	return file, line
+}
+
+func (b *binaryReader) bytes(n int) []byte {
+	data := make([]byte, n)
+	if b.err == nil {
+		_, err := io.ReadFull(b.reader, data)
+		b.fail(err)
+	}
+	return data
+}
+
+func (b *binaryReader) fail(err error) {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if b.err == nil && err != nil {
+		b.err = fmt.Errorf("Error reading binary debug file: %w", err)
+	}
+}
+
+// binaryRoutine collects a routine from the records describing it, which give its code
+// addresses as offsets into the code area
+type binaryRoutine struct {
+	name   string
+	start  int
+	end    int
+	locals []string
+	lines  []binaryLine
+}
+
+type binaryLine struct {
+	offset int // From the start of the routine
+	file   int
+	line   int
+}
+
+// readBinary parses the binary format written by Inform 6.32 and earlier. Globals are numbered
+// and code addresses are offsets, so the header and memory map records in the file are used to
+// place them in the story.
+func readBinary(reader *bufio.Reader) (*Info, error) {
+	b := &binaryReader{reader: reader}
+	magic := b.bytes(2)
+	if b.err == nil && (magic[0] != binaryMagic[0] || magic[1] != binaryMagic[1]) {
+		return nil, errors.New("Not a binary debug file")
+	}
+	b.word() // Format version
+	b.word() // Inform version
+
+	info := newInfo()
+	files := map[int]string{}
+	globals := map[int]string{}
+	routines := map[int]*binaryRoutine{}
+	order := []int{}
+	header := []byte(nil)
+	codeArea := 0
+
+	numbered := func(number int) *binaryRoutine {
+		if _, ok := routines[number]; !ok {
+			routines[number] = &binaryRoutine{}
+			order = append(order, number)
+		}
+		return routines[number]
+	}
+
+	for record := b.byte(); b.err == nil && record != dbr_EOF; record = b.byte() {
+		switch record {
+		case dbr_File:
+			number := b.byte()
+			b.string() // Name given in the source
+			files[number] = b.string()
+		case dbr_Class:
+			b.string()
+			b.line()
+			b.line()
+		case dbr_Object:
+			number := b.word()
+			info.objects[number] = b.string()
+			b.line()
+			b.line()
+		case dbr_Global:
+			number := b.byte()
+			globals[number] = b.string()
+		case dbr_Attribute:
+			number := b.word()
+			info.attributes[number] = b.string()
+		case dbr_Property:
+			number := b.word()
+			info.properties[number] = b.string()
+		case dbr_FakeAction, dbr_Action, dbr_Array:
+			b.word()
+			b.string()
+		case dbr_Header:
+			header = b.bytes(headerLength)
+		case dbr_LineRef:
+			r := numbered(b.word())
+			for range b.word() {
+				file, line := b.line()
+				r.lines = append(r.lines, binaryLine{offset: b.word(), file: file, line: line})
+			}
+		case dbr_Routine:
+			r := numbered(b.word())
+			b.line()
+			r.start = b.address()
+			r.name = b.string()
+			for local := b.string(); b.err == nil && local != ""; local = b.string() {
+				r.locals = append(r.locals, local)
+			}
+		case dbr_Map:
+			for name := b.string(); b.err == nil && name != ""; name = b.string() {
+				address := b.address()
+				if name == "code area" {
+					codeArea = address
+				}
+			}
+		case dbr_RoutineEnd:
+			r := numbered(b.word())
+			b.line()
+			r.end = b.address()
+		default:
+			return nil, fmt.Errorf("Unknown record type %d in binary debug file", record)
+		}
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if header == nil {
+		return nil, errors.New("Binary debug file has no story header")
+	}
+
+	m, err := memory.NewMemoryFromBytes(header, func(*memory.Memory) {})
+	if err != nil {
+		return nil, err
+	}
+	globalsAddress := m.GetGlobalsAddress()
+	for number, name := range globals {
+		info.globals[globalsAddress.OffsetWords(number)] = name
+	}
+
+	for _, number := range order {
+		r := routines[number]
+		address := memory.Address(codeArea + r.start)
+		routine := Routine{
+			Name:    r.name,
+			Packed:  m.PackRoutineAddress(address),
+			Address: address,
+			Length:  max(r.end-r.start, 0),
+			Locals:  r.locals,
+		}
+		for _, line := range r.lines {
+			routine.Lines = append(routine.Lines, Line{
+				Address: address.OffsetBytes(line.offset),
+				File:    files[line.file],
+				Line:    line.line,
+			})
+		}
+		slices.SortFunc(routine.Lines, func(a Line, b Line) int { return int(a.Address) - int(b.Address) })
+		info.routines = append(info.routines, routine)
+	}
+
+	info.index()
+	return info, nil
+}
@@ -0,0 +1,248 @@
+// Package debuginfo reads the debug information file Inform writes alongside a story, usually
+// named gameinfo.dbg, which gives names to the story's routines, variables and objects.
+package debuginfo
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+type word = uint16
+
+// Routine is a routine in the story, with its locals and the source lines its code came from
+type Routine struct {
+	Name    string
+	Packed  word // Packed address, as used by call instructions
+	Address memory.Address
+	Length  int
+	Locals  []string // Local names, starting with local 0
+	Lines   []Line   // Source lines in address order
+}
+
+// Line is the source line that compiled to the code starting at an address
+type Line struct {
+	Address memory.Address
+	File    string
+	Line    int
+}
+
+// Info holds the names from a debug information file
+type Info struct {
+	routines   []Routine // In address order
+	packed     map[word]int
+	globals    map[memory.Address]string
+	objects    map[int]string
+	attributes map[int]string
+	properties map[int]string
+}
+
+// The XML format written by Inform 6.33 and later. Only the elements used for names are read.
+type xmlStory struct {
+	XMLName    xml.Name      `xml:"inform-story-file"`
+	Sources    []xmlSource   `xml:"source"`
+	Routines   []xmlRoutine  `xml:"routine"`
+	Globals    []xmlGlobal   `xml:"global-variable"`
+	Objects    []xmlConstant `xml:"object"`
+	Attributes []xmlConstant `xml:"attribute"`
+	Properties []xmlConstant `xml:"property"`
+}
+
+type xmlSource struct {
+	Index     int    `xml:"index,attr"`
+	GivenPath string `xml:"given-path"`
+}
+
+type xmlConstant struct {
+	Identifier string `xml:"identifier"`
+	Value      int    `xml:"value"`
+}
+
+type xmlGlobal struct {
+	Identifier string `xml:"identifier"`
+	Address    int    `xml:"address"`
+}
+
+type xmlRoutine struct {
+	Identifier     string             `xml:"identifier"`
+	Value          int                `xml:"value"`
+	Address        int                `xml:"address"`
+	ByteCount      int                `xml:"byte-count"`
+	Locals         []xmlLocal         `xml:"local-variable"`
+	SequencePoints []xmlSequencePoint `xml:"sequence-point"`
+}
+
+type xmlLocal struct {
+	Identifier string `xml:"identifier"`
+	Index      int    `xml:"index"`
+}
+
+type xmlSequencePoint struct {
+	Address  int         `xml:"address"`
+	Location xmlLocation `xml:"source-code-location"`
+}
+
+type xmlLocation struct {
+	FileIndex int `xml:"file-index"`
+	Line      int `xml:"line"`
+}
+
+// Read parses a debug information file, either in the XML format written by Inform 6.33 and
+// later or in the binary format written by earlier versions
+func Read(r io.Reader) (*Info, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(2)
+	if err == nil && magic[0] == binaryMagic[0] && magic[1] == binaryMagic[1] {
+		return readBinary(reader)
+	}
+
+	story := xmlStory{}
+	err = xml.NewDecoder(reader).Decode(&story)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newInfo()
+	info.objects = constants(story.Objects)
+	info.attributes = constants(story.Attributes)
+	info.properties = constants(story.Properties)
+
+	files := map[int]string{}
+	for _, source := range story.Sources {
+		files[source.Index] = source.GivenPath
+	}
+
+	for _, global := range story.Globals {
+		info.globals[memory.Address(global.Address)] = strings.TrimSpace(global.Identifier)
+	}
+
+	for _, r := range story.Routines {
+		routine := Routine{
+			Name:    strings.TrimSpace(r.Identifier),
+			Packed:  word(r.Value),
+			Address: memory.Address(r.Address),
+			Length:  r.ByteCount,
+		}
+
+		for _, local := range r.Locals {
+			// Z-code locals are numbered from 1
+			for len(routine.Locals) < local.Index {
+				routine.Locals = append(routine.Locals, "")
+			}
+			if local.Index > 0 {
+				routine.Locals[local.Index-1] = strings.TrimSpace(local.Identifier)
+			}
+		}
+
+		for _, point := range r.SequencePoints {
+			routine.Lines = append(routine.Lines, Line{
+				Address: memory.Address(point.Address),
+				File:    files[point.Location.FileIndex],
+				Line:    point.Location.Line,
+			})
+		}
+		slices.SortFunc(routine.Lines, func(a Line, b Line) int { return int(a.Address) - int(b.Address) })
+
+		info.routines = append(info.routines, routine)
+	}
+
+	info.index()
+	return info, nil
+}
+
+func newInfo() *Info {
+	return &Info{
+		packed:     map[word]int{},
+		globals:    map[memory.Address]string{},
+		objects:    map[int]string{},
+		attributes: map[int]string{},
+		properties: map[int]string{},
+	}
+}
+
+// index sorts the routines by address and finds them by packed address
+func (info *Info) index() {
+	slices.SortFunc(info.routines, func(a Routine, b Routine) int { return int(a.Address) - int(b.Address) })
+	for i, routine := range info.routines {
+		info.packed[routine.Packed] = i
+	}
+}
+
+func constants(list []xmlConstant) map[int]string {
+	names := make(map[int]string, len(list))
+	for _, constant := range list {
+		names[constant.Value] = strings.TrimSpace(constant.Identifier)
+	}
+	return names
+}
+
+// Routine finds a routine by the packed address used to call it
+func (info *Info) Routine(packed word) (Routine, bool) {
+	i, ok := info.packed[packed]
+	if !ok {
+		return Routine{}, false
+	}
+	return info.routines[i], true
+}
+
+// RoutineName is the name of the routine with a packed address, or "" if it isn't known
+func (info *Info) RoutineName(packed word) string {
+	routine, _ := info.Routine(packed)
+	return routine.Name
+}
+
+// RoutineAt finds the routine whose code includes an address
+func (info *Info) RoutineAt(address memory.Address) (Routine, bool) {
+	i, found := slices.BinarySearchFunc(info.routines, address, func(routine Routine, address memory.Address) int {
+		return int(routine.Address) - int(address)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || int(address) >= int(info.routines[i].Address)+info.routines[i].Length {
+		return Routine{}, false
+	}
+	return info.routines[i], true
+}
+
+// LineAt finds the source line that compiled to the code at an address
+func (info *Info) LineAt(address memory.Address) (Line, bool) {
+	routine, ok := info.RoutineAt(address)
+	if !ok {
+		return Line{}, false
+	}
+
+	line, found := Line{}, false
+	for _, point := range routine.Lines {
+		if point.Address > address {
+			break
+		}
+		line, found = point, true
+	}
+	return line, found
+}
+
+// Global names the global variable stored at an address in the globals table
+func (info *Info) Global(address memory.Address) (string, bool) {
+	name, ok := info.globals[address]
+	return name, ok
+}
+
+func (info *Info) Object(number int) (string, bool) {
+	name, ok := info.objects[number]
+	return name, ok
+}
+
+func (info *Info) Attribute(number int) (string, bool) {
+	name, ok := info.attributes[number]
+	return name, ok
+}
+
+func (info *Info) Property(number int) (string, bool) {
+	name, ok := info.properties[number]
+	return name, ok
+}
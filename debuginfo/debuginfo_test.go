@@ -0,0 +1,165 @@
+package debuginfo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+const testDebugFile = `<?xml version="1.0" encoding="UTF-8"?>
+<inform-story-file version="1.0" content-creator="Inform" content-creator-version="6.42">
+<source index="0"><given-path>game.inf</given-path><language>Inform 6</language></source>
+<attribute><identifier>light</identifier><value>17</value></attribute>
+<property><identifier>description</identifier><value>8</value></property>
+<object><identifier>brass_lantern</identifier><value>27</value></object>
+<global-variable><identifier>location</identifier><address>700</address></global-variable>
+<routine>
+  <identifier>Main</identifier><value>600</value><address>1200</address><byte-count>40</byte-count>
+  <local-variable><identifier>count</identifier><index>2</index></local-variable>
+  <sequence-point><address>1210</address><source-code-location><file-index>0</file-index><line>12</line></source-code-location></sequence-point>
+  <sequence-point><address>1201</address><source-code-location><file-index>0</file-index><line>10</line></source-code-location></sequence-point>
+</routine>
+<routine>
+  <identifier artificial="true">Other</identifier><value>640</value><address>1280</address><byte-count>4</byte-count>
+</routine>
+</inform-story-file>`
+
+func TestRead_Names(t *testing.T) {
+	info, err := Read(strings.NewReader(testDebugFile))
+	testassert.NoError(t, err)
+
+	name, ok := info.Attribute(17)
+	testassert.True(t, ok)
+	testassert.Same(t, "light", name)
+
+	name, _ = info.Property(8)
+	testassert.Same(t, "description", name)
+	name, _ = info.Object(27)
+	testassert.Same(t, "brass_lantern", name)
+	name, _ = info.Global(700)
+	testassert.Same(t, "location", name)
+	_, ok = info.Object(28)
+	testassert.False(t, ok)
+
+	testassert.Same(t, "Main", info.RoutineName(600))
+	testassert.Same(t, "Other", info.RoutineName(640))
+	testassert.Same(t, "", info.RoutineName(601))
+
+	main, _ := info.Routine(600)
+	testassert.Same(t, 2, len(main.Locals))
+	testassert.Same(t, "count", main.Locals[1])
+}
+
+func TestRoutineAt_FindsContainingRoutine(t *testing.T) {
+	info, err := Read(strings.NewReader(testDebugFile))
+	testassert.NoError(t, err)
+
+	type spec struct {
+		address memory.Address
+		name    string
+	}
+
+	tests := map[string]spec{
+		"before first":  {address: 1199, name: ""},
+		"routine start": {address: 1200, name: "Main"},
+		"inside":        {address: 1239, name: "Main"},
+		"gap":           {address: 1240, name: ""},
+		"second":        {address: 1283, name: "Other"},
+		"after last":    {address: 1284, name: ""},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			routine, _ := info.RoutineAt(s.address)
+			testassert.Same(t, s.name, routine.Name)
+		})
+	}
+}
+
+func TestLineAt_UsesPrecedingSequencePoint(t *testing.T) {
+	info, err := Read(strings.NewReader(testDebugFile))
+	testassert.NoError(t, err)
+
+	line, ok := info.LineAt(1215)
+	testassert.True(t, ok)
+	testassert.Same(t, "game.inf", line.File)
+	testassert.Same(t, 12, line.Line)
+
+	_, ok = info.LineAt(1200)
+	testassert.False(t, ok)
+}
+
+// binaryDebugFile writes the same names as testDebugFile in the binary format, for a V5 story
+// with globals at 640 and code starting at 1024
+func binaryDebugFile() []byte {
+	data := []byte{0xde, 0xbf, 0x00, 0x00, 0x06, 0x3c}
+	str := func(s string) []byte { return append([]byte(s), 0) }
+	wrd := func(w int) []byte { return []byte{byte(w >> 8), byte(w)} }
+	addr := func(a int) []byte { return []byte{byte(a >> 16), byte(a >> 8), byte(a)} }
+	line := func(l int) []byte { return []byte{0, byte(l >> 8), byte(l), 0} }
+	record := func(fields ...[]byte) {
+		for _, field := range fields {
+			data = append(data, field...)
+		}
+	}
+
+	header := make([]byte, 64)
+	header[0] = 5
+	header[0x0c], header[0x0d] = 0x02, 0x80
+
+	record([]byte{9}, header)
+	record([]byte{1, 0}, str("game"), str("game.inf"))
+	record([]byte{5}, wrd(17), str("light"))
+	record([]byte{6}, wrd(8), str("description"))
+	record([]byte{3}, wrd(27), str("brass_lantern"), line(3), line(5))
+	record([]byte{4, 30}, str("location"))
+	record([]byte{11}, wrd(0), line(9), addr(176), str("Main"), str("x"), str("count"), []byte{0})
+	record([]byte{10}, wrd(0), wrd(2), line(12), wrd(10), line(10), wrd(1))
+	record([]byte{14}, wrd(0), line(14), addr(216))
+	record([]byte{11}, wrd(1), line(20), addr(256), str("Other"), []byte{0})
+	record([]byte{14}, wrd(1), line(21), addr(260))
+	record([]byte{12}, wrd(900), str("buffer"))
+	record([]byte{13}, str("code area"), addr(1024), str("strings area"), addr(4096), []byte{0})
+	record([]byte{0})
+	return data
+}
+
+func TestRead_BinaryFormat(t *testing.T) {
+	info, err := Read(bytes.NewReader(binaryDebugFile()))
+	testassert.NoError(t, err)
+
+	name, _ := info.Attribute(17)
+	testassert.Same(t, "light", name)
+	name, _ = info.Property(8)
+	testassert.Same(t, "description", name)
+	name, _ = info.Object(27)
+	testassert.Same(t, "brass_lantern", name)
+	name, ok := info.Global(700)
+	testassert.True(t, ok)
+	testassert.Same(t, "location", name)
+
+	// Routine addresses are offsets into the code area
+	testassert.Same(t, "Main", info.RoutineName(300))
+	testassert.Same(t, "Other", info.RoutineName(320))
+	main, _ := info.Routine(300)
+	testassert.Same(t, memory.Address(1200), main.Address)
+	testassert.Same(t, 40, main.Length)
+	testassert.Same(t, "count", main.Locals[1])
+
+	routine, _ := info.RoutineAt(1283)
+	testassert.Same(t, "Other", routine.Name)
+
+	line, ok := info.LineAt(1215)
+	testassert.True(t, ok)
+	testassert.Same(t, "game.inf", line.File)
+	testassert.Same(t, 12, line.Line)
+}
+
+func TestRead_BinaryFormatTruncated(t *testing.T) {
+	data := binaryDebugFile()
+	_, err := Read(bytes.NewReader(data[:len(data)-10]))
+	testassert.ErrorMessage(t, "Error reading binary debug file: unexpected EOF", err)
+}
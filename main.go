@@ -4,15 +4,53 @@
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/Drakmyth/golang-zmachine/debuginfo"
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/screen"
+	"github.com/Drakmyth/golang-zmachine/sound"
 	"github.com/Drakmyth/golang-zmachine/zmachine"
 	"github.com/spf13/cobra"
 )
 
 var debug bool
+var debugFilePath string
+var blorbPath string
+var soundDir string
+var seed uint64
+var errorLevel int
+var crashDumpPath string
+var tracePath string
+var traceRoutines string
+var traceOpcodes []string
+var profilePath string
+var profileFormat string
+var mouse bool
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Print execution instructions")
+	rootCmd.PersistentFlags().StringVar(&debugFilePath, "debug-file", "", "Inform debug information file (gameinfo.dbg) naming the story's routines, variables and objects")
+	rootCmd.Flags().StringVar(&blorbPath, "blorb", "", "Blorb file to take sound effects from")
+	rootCmd.Flags().StringVar(&crashDumpPath, "crash-dump", "", "File a crash dump is written to on a fatal error (default <story>.crash.json)")
+	rootCmd.Flags().IntVarP(&errorLevel, "error-level", "Z", int(zmachine.EL_ReportOnce),
+		"Runtime error handling: 0 ignore, 1 report first of each, 2 report all, 3 halt")
+	rootCmd.Flags().BoolVar(&mouse, "mouse", false, "Scroll back through history with the mouse wheel, at the cost of the terminal's text selection")
+	rootCmd.Flags().Uint64Var(&seed, "seed", 0, "Seed random numbers so every run of the story plays out the same")
+	rootCmd.Flags().StringVar(&soundDir, "sound-dir", "", "Directory sound effects from the Blorb file are written to (default a new temporary directory for the story)")
+	rootCmd.Flags().StringVar(&tracePath, "trace", "", "Write each executed instruction to a file as a line of JSON")
+	rootCmd.Flags().StringVar(&traceRoutines, "trace-routines", "", "Only trace routines with addresses in a range, such as 0x4f00-0x5200")
+	rootCmd.Flags().StringSliceVar(&traceOpcodes, "trace-opcodes", nil, "Only trace these opcodes, such as call_vs,je")
+	rootCmd.Flags().StringVar(&profilePath, "profile", "", "Write instruction counts and time spent in each routine to a file when the story ends")
+	rootCmd.Flags().StringVar(&profileFormat, "profile-format", "text", "Profile format: text for a report, or pprof for go tool pprof")
+
+	replayDumpCmd.Flags().StringVar(&tracePath, "trace", "", "Write each instruction replayed to a file as a line of JSON")
+	replayDumpCmd.Flags().StringVar(&traceRoutines, "trace-routines", "", "Only trace routines with addresses in a range, such as 0x4f00-0x5200")
+	replayDumpCmd.Flags().StringSliceVar(&traceOpcodes, "trace-opcodes", nil, "Only trace these opcodes, such as call_vs,je")
+
+	rootCmd.AddCommand(replayDumpCmd)
 }
 
 var rootCmd = &cobra.Command{
@@ -35,15 +73,230 @@ func init() {
 		}
 
 		interpreter.Debug = debug
+		interpreter.Screen.CaptureMouse(mouse)
+		interpreter.ErrorLevel = zmachine.ErrorLevel(min(max(errorLevel, 0), int(zmachine.EL_Halt)))
+
+		var info *debuginfo.Info
+		if debugFilePath != "" {
+			info, err = loadDebugInfo(debugFilePath)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				interpreter.Shutdown(1)
+			}
+			interpreter.UseDebugInfo(info)
+		}
+
+		if cmd.Flags().Changed("seed") {
+			interpreter.Random = zmachine.NewRNG(func() uint64 { return seed })
+		}
+
+		if tracePath != "" {
+			tracer, err := openTracer(tracePath, traceRoutines, traceOpcodes)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				interpreter.Shutdown(1)
+			}
+			interpreter.Tracer = tracer
+		}
+
+		if profilePath != "" {
+			profiler, err := openProfiler(interpreter, profilePath, profileFormat)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				interpreter.Shutdown(1)
+			}
+			if info != nil {
+				profiler.Names = info.RoutineName
+			}
+			interpreter.Profiler = profiler
+		}
+
+		if blorbPath != "" {
+			player, err := loadSoundPlayer(blorbPath, soundDir, args[0])
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				interpreter.Shutdown(1)
+			}
+			player.Bell = interpreter.Screen.Beep
+			interpreter.UseSoundPlayer(player)
+		}
 
 		err = interpreter.Run()
+		if err != nil {
+			// Close the screen first so the error is still visible afterwards
+			interpreter.Screen.End()
+			fmt.Fprintln(os.Stderr, err)
+			if interpreter.Tracer != nil {
+				interpreter.Tracer.Close()
+			}
+			if interpreter.Profiler != nil {
+				interpreter.Profiler.Close()
+			}
+
+			zerr := &zmachine.ZMachineError{}
+			if errors.As(err, &zerr) {
+				fmt.Fprint(os.Stderr, zerr.StackTrace())
+			}
+
+			path := crashDumpPath
+			if path == "" {
+				path = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + ".crash.json"
+			}
+			writeCrashDump(interpreter, err, path)
+			os.Exit(1)
+		}
+	},
+}
+
+var replayDumpCmd = &cobra.Command{
+	Use:   "replay-dump <dump-file>",
+	Short: "Open a crash dump in the debugger",
+	Long: `Print the error, recent instructions and call stack recorded in a crash dump, then load the
+story in the debugger at the instruction that failed. The story's text is printed along with the
+debugger's, and it can be stepped through, given input and examined. Use --trace to record the
+instructions replayed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dump, err := zmachine.ReadCrashDump(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		m, err := memory.NewMemoryFromFile(dump.Story, func(m *memory.Memory) {})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// The terminal is left to the debugger, so the story draws to a simulated screen
+		s, sim := screen.NewSimulationScreen(80, 25)
+		interpreter, err := zmachine.NewZMachine(m, s)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		interpreter.ErrorLevel = zmachine.EL_Halt
+
+		if debugFilePath != "" {
+			info, err := loadDebugInfo(debugFilePath)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				interpreter.Shutdown(1)
+			}
+			interpreter.UseDebugInfo(info)
+		}
+
+		if tracePath != "" {
+			tracer, err := openTracer(tracePath, traceRoutines, traceOpcodes)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				interpreter.Shutdown(1)
+			}
+			interpreter.Tracer = tracer
+		}
+
+		err = interpreter.RestoreCrashDump(dump)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err)
 			interpreter.Shutdown(1)
 		}
+
+		fmt.Print(dump.Summary())
+		fmt.Println("\nType help for the debugger's commands.")
+		zmachine.NewDebugger(interpreter, sim, os.Stdin, os.Stdout).Run()
+
+		interpreter.Screen.End()
+		if interpreter.Tracer != nil {
+			interpreter.Tracer.Close()
+		}
 	},
 }
 
+func writeCrashDump(interpreter *zmachine.ZMachine, err error, path string) {
+	dump, dumpErr := interpreter.NewCrashDump(err)
+	if dumpErr == nil {
+		dumpErr = dump.WriteFile(path)
+	}
+
+	if dumpErr != nil {
+		fmt.Fprintln(os.Stderr, "Unable to write crash dump:", dumpErr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Crash dump written to %s, replay it with: zmachine replay-dump %s\n", path, path)
+}
+
+func loadDebugInfo(path string) (*debuginfo.Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return debuginfo.Read(file)
+}
+
+func openTracer(path string, routines string, opcodes []string) (*zmachine.Tracer, error) {
+	filter := zmachine.TraceFilter{Opcodes: opcodes}
+	if routines != "" {
+		start, end, found := strings.Cut(routines, "-")
+		first, err := strconv.ParseUint(start, 0, 32)
+		if err != nil || !found {
+			return nil, fmt.Errorf("Invalid routine range %q", routines)
+		}
+		last, err := strconv.ParseUint(end, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid routine range %q", routines)
+		}
+		filter.RoutineStart, filter.RoutineEnd = memory.Address(first), memory.Address(last)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return zmachine.NewTracer(file, filter), nil
+}
+
+func openProfiler(interpreter *zmachine.ZMachine, path string, format string) (*zmachine.Profiler, error) {
+	formats := map[string]zmachine.ProfileFormat{"text": zmachine.PF_Text, "pprof": zmachine.PF_Pprof}
+	profileFormat, ok := formats[format]
+	if !ok {
+		return nil, fmt.Errorf("Unknown profile format %q", format)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return zmachine.NewProfiler(interpreter.Memory, file, profileFormat), nil
+}
+
+func loadSoundPlayer(blorbPath string, dir string, storyPath string) (*sound.FilePlayer, error) {
+	if dir == "" {
+		// Keep each story's sounds apart, and out of the working directory
+		story := strings.TrimSuffix(filepath.Base(storyPath), filepath.Ext(storyPath))
+		var err error
+		dir, err = os.MkdirTemp("", story+"-sounds-")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(blorbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	blorb, err := sound.ReadBlorb(file)
+	if err != nil {
+		return nil, err
+	}
+	return sound.NewFilePlayer(blorb, dir), nil
+}
+
 func main() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -114,10 +114,41 @@
 	_
 )
 
+// Header extension table word indices (V5+)
+const (
+	HdrExt_W_Size = iota
+	HdrExt_W_MouseX
+	HdrExt_W_MouseY
+	HdrExt_W_UnicodeTable
+	HdrExt_W_Flags3
+	HdrExt_W_TrueFGColor
+	HdrExt_W_TrueBGColor
+)
+
 func (m Memory) GetVersion() int {
 	return int(m.ReadByte(Addr_ROM_B_Version))
 }
 
+func (m Memory) GetReleaseNumber() word {
+	return m.ReadWord(Addr_ROM_W_ReleaseNumber)
+}
+
+func (m Memory) GetSerialCode() [6]byte {
+	return [6]byte(m.GetBytes(Addr_ROM_S_SerialCode, 6))
+}
+
+func (m Memory) GetChecksum() word {
+	return m.ReadWord(Addr_ROM_W_Checksum)
+}
+
+func (m Memory) GetHighMemoryAddress() Address {
+	return Address(m.ReadWord(Addr_ROM_A_HighMem))
+}
+
+func (m Memory) GetStaticMemoryAddress() Address {
+	return Address(m.ReadWord(Addr_ROM_A_StaticMem))
+}
+
 func (m Memory) GetInitialProgramCounter() Address {
 	return Address(m.ReadWord(Addr_ROM_A_InitialProgramCounter))
 }
@@ -130,10 +161,41 @@ func (m Memory) GetAbbreviationsAddress() Address {
 	return Address(m.ReadWord(Addr_ROM_A_AbbreviationsTable))
 }
 
+func (m Memory) GetDictionaryAddress() Address {
+	return Address(m.ReadWord(Addr_ROM_A_Dictionary))
+}
+
 func (m Memory) GetObjectsAddress() Address {
 	return Address(m.ReadWord(Addr_ROM_A_ObjectTable))
 }
 
+func (m Memory) GetTerminatingCharactersAddress() Address {
+	return Address(m.ReadWord(Addr_ROM_A_TermChars))
+}
+
+func (m Memory) GetHeaderExtensionAddress() Address {
+	return Address(m.ReadWord(Addr_ROM_A_HeaderExtension))
+}
+
+// GetHeaderExtensionWord returns 0 for any word the story's header extension table doesn't include
+func (m Memory) GetHeaderExtensionWord(index int) word {
+	extAddr := m.GetHeaderExtensionAddress()
+	if m.GetVersion() < 5 || extAddr == 0 {
+		return 0
+	}
+
+	size := int(m.ReadWord(extAddr.OffsetWords(HdrExt_W_Size)))
+	if index > size {
+		return 0
+	}
+
+	return m.ReadWord(extAddr.OffsetWords(index))
+}
+
+func (m Memory) GetUnicodeTableAddress() Address {
+	return Address(m.GetHeaderExtensionWord(HdrExt_W_UnicodeTable))
+}
+
 func (m Memory) GetFlag1Bits(bits Flags1) bool {
 	return m.ReadByte(Addr_IROM_B_Flags1)&byte(bits) != 0
 }
@@ -144,10 +206,22 @@ func (m Memory) SetFlag1Bits(bits Flags1) {
 	m.WriteByte(Addr_IROM_B_Flags1, flags1)
 }
 
+func (m Memory) ClearFlag1Bits(bits Flags1) {
+	flags1 := m.ReadByte(Addr_IROM_B_Flags1)
+	flags1 &^= byte(bits)
+	m.WriteByte(Addr_IROM_B_Flags1, flags1)
+}
+
 func (m Memory) GetFlag2Bits(bits Flags2) bool {
 	return m.ReadWord(Addr_RAM_W_Flags2)&word(bits) != 0
 }
 
+func (m Memory) ClearFlag2Bits(bits Flags2) {
+	flags2 := m.ReadWord(Addr_RAM_W_Flags2)
+	flags2 &^= word(bits)
+	m.WriteWord(Addr_RAM_W_Flags2, flags2)
+}
+
 func (m Memory) SetFlag2Bits(bits Flags2) {
 	flags2 := m.ReadWord(Addr_RAM_W_Flags2)
 	flags2 |= word(bits)
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"errors"
 	"os"
 	"slices"
 
@@ -14,7 +15,16 @@ type Memory struct {
 	path        string
 	version     int
 	memory      []byte
+	original    []byte // The story file as loaded, before any changes
 	initialized bool
+	journal     []change // What writes since the last checkpoint overwrote, oldest first
+	journaling  bool
+}
+
+// change is a byte of memory as it was before a write
+type change struct {
+	address Address
+	value   byte
 }
 
 func NewMemoryFromFile(path string, initializer func(*Memory)) (*Memory, error) {
@@ -24,10 +34,26 @@ func NewMemoryFromFile(path string, initializer func(*Memory)) (*Memory, error)
 		return nil, err
 	}
 
+	m, err := NewMemoryFromBytes(bytes, initializer)
+	if err != nil {
+		return nil, err
+	}
+
+	m.path = path
+	return m, nil
+}
+
+// NewMemoryFromBytes loads a story that's already in memory, such as one built by a test. The
+// memory takes ownership of the bytes.
+func NewMemoryFromBytes(bytes []byte, initializer func(*Memory)) (*Memory, error) {
+	if len(bytes) == 0 {
+		return nil, errors.New("Story is empty")
+	}
+
 	m := Memory{
-		path:        path,
 		version:     int(bytes[0]),
 		memory:      bytes,
+		original:    slices.Clone(bytes),
 		initialized: false,
 	}
 
@@ -37,10 +63,87 @@ func NewMemoryFromFile(path string, initializer func(*Memory)) (*Memory, error)
 	return &m, nil
 }
 
-// TODO: This might be needed to support checksum verification. See `verify` opcode.
-// func (m Memory) OriginalFileState() (*Memory, error) {
-// 	return NewMemoryFromFile(m.path, func(memory *Memory) {})
-// }
+// CalculateChecksum sums the story file as loaded, from the end of the header up to the file
+// length the header gives, for verify to compare with the checksum in the header. A length of
+// 0, which some early stories have, covers the whole file.
+func (m Memory) CalculateChecksum() word {
+	scale := 2
+	switch {
+	case m.version >= 6:
+		scale = 8
+	case m.version >= 4:
+		scale = 4
+	}
+
+	length := int(m.ReadWord(Addr_ROM_W_FileLength)) * scale
+	if length == 0 || length > len(m.original) {
+		length = len(m.original)
+	}
+
+	sum := word(0)
+	for _, b := range m.original[min(0x40, length):length] {
+		sum += word(b)
+	}
+	return sum
+}
+
+func (m Memory) GetPath() string {
+	return m.path
+}
+
+// Size is the length of the story in bytes
+func (m Memory) Size() int {
+	return len(m.memory)
+}
+
+// GetDynamicMemory returns a copy of dynamic memory, which runs up to the start of static memory
+func (m Memory) GetDynamicMemory() []byte {
+	return slices.Clone(m.memory[:m.GetStaticMemoryAddress()])
+}
+
+// GetOriginalDynamicMemory returns dynamic memory as it was in the story file
+func (m Memory) GetOriginalDynamicMemory() []byte {
+	return slices.Clone(m.original[:m.GetStaticMemoryAddress()])
+}
+
+// SetDynamicMemory replaces the whole of dynamic memory, as when restoring a saved game
+func (m *Memory) SetDynamicMemory(data []byte) error {
+	if len(data) != int(m.GetStaticMemoryAddress()) {
+		return errors.New("Data is not the same length as dynamic memory")
+	}
+
+	m.record(0, len(data))
+	copy(m.memory, data)
+	return nil
+}
+
+// Checkpoint starts recording what writes overwrite, forgetting anything recorded before, so
+// dynamic memory as it is now can be recovered with DynamicMemoryAtCheckpoint
+func (m *Memory) Checkpoint() {
+	m.journal = m.journal[:0]
+	m.journaling = true
+}
+
+// DynamicMemoryAtCheckpoint returns a copy of dynamic memory as it was at the last checkpoint,
+// or as it is now if there hasn't been one
+func (m Memory) DynamicMemoryAtCheckpoint() []byte {
+	data := m.GetDynamicMemory()
+	for i := len(m.journal) - 1; i >= 0; i-- {
+		if c := m.journal[i]; int(c.address) < len(data) {
+			data[c.address] = c.value
+		}
+	}
+	return data
+}
+
+func (m *Memory) record(address Address, length int) {
+	if !m.journaling {
+		return
+	}
+	for i := range length {
+		m.journal = append(m.journal, change{address.OffsetBytes(i), m.memory[address.OffsetBytes(i)]})
+	}
+}
 
 func (m Memory) GetBytes(address Address, length int) []byte {
 	assert.True(m.initialized, "Cannot call Memory#GetBytes during memory initialization!")
@@ -53,6 +156,7 @@ func (m Memory) GetBytesNext(address Address, length int) ([]byte, Address) {
 
 func (m *Memory) SetBytes(address Address, data []byte) {
 	assert.True(m.initialized, "Cannot call Memory#SetBytes during memory initialization!")
+	m.record(address, len(data))
 	m.memory = slices.Replace(m.memory, int(address), int(address)+len(data), data...)
 }
 
@@ -91,6 +195,7 @@ func (m Memory) ReadWordNext(address Address) (word, Address) {
 // representative of its behavior nor to disable the stdmethods check entirely.
 func (m *Memory) WriteByte(address Address, data byte) Address {
 	// TODO: Error when writing to ROM, or IROM when initialized is false
+	m.record(address, 1)
 	m.memory[address] = data
 	return address.OffsetBytes(1)
 }
@@ -110,6 +215,22 @@ func (m Memory) StringPackedAddress(address word) Address {
 	return m.packedAddress(address, m.ReadWord(Addr_ROM_W_StringsOffset))
 }
 
+// PackRoutineAddress is the inverse of RoutinePackedAddress, giving the packed address a call
+// would use to reach the routine at an address
+func (m Memory) PackRoutineAddress(address Address) word {
+	switch m.version {
+	case 1, 2, 3:
+		return word(address / 2)
+	case 4, 5:
+		return word(address / 4)
+	case 6, 7:
+		return word((address - 8*Address(m.ReadWord(Addr_ROM_W_RoutinesOffset))) / 4)
+	case 8:
+		return word(address / 8)
+	}
+	panic("Unknown version")
+}
+
 // NOTE: packedAddresses won't be calculated correctly for relative memory, only absolute memory
 func (m Memory) packedAddress(address word, offset word) Address {
 	switch m.version {
@@ -150,3 +271,20 @@ func (m *Memory) GetAbbreviation(bank int, index int) zstring.ZString {
 	abbreviation := m.GetZString(Address(address * 2))
 	return abbreviation
 }
+
+func (m Memory) GetUnicodeTable() (zstring.UnicodeTable, error) {
+	address := m.GetUnicodeTableAddress()
+	if address == 0 {
+		return zstring.GetDefaultUnicodeTable(m.GetVersion()), nil
+	}
+
+	count, next_address := m.ReadByteNext(address)
+	extras := make([]rune, 0, count)
+	for range count {
+		var r word
+		r, next_address = m.ReadWordNext(next_address)
+		extras = append(extras, rune(r))
+	}
+
+	return zstring.NewUnicodeTable(m.GetVersion(), extras)
+}
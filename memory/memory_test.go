@@ -48,3 +48,55 @@ func TestMemory_ReadWordNextAddress(t *testing.T) {
 	_, next_address := m.ReadWordNext(address)
 	testassert.Same(t, address.OffsetWords(1), next_address)
 }
+
+func TestMemory_NewMemoryFromBytes(t *testing.T) {
+	story := make([]byte, 0x40)
+	story[0] = 5
+
+	m, err := NewMemoryFromBytes(story, func(m *Memory) {})
+	testassert.NoError(t, err)
+	testassert.Same(t, 5, m.GetVersion())
+	testassert.Same(t, 0x40, m.Size())
+
+	_, err = NewMemoryFromBytes([]byte{}, func(m *Memory) {})
+	testassert.ErrorMessage(t, "Story is empty", err)
+}
+
+func TestMemory_DynamicMemoryAtCheckpoint(t *testing.T) {
+	story := make([]byte, 0x80)
+	story[0x0e], story[0x0f] = 0x00, 0x60
+	m, err := NewMemoryFromBytes(story, func(m *Memory) {})
+	testassert.NoError(t, err)
+
+	m.WriteByte(0x40, 1)
+	m.Checkpoint()
+	m.WriteByte(0x40, 2)
+	m.WriteWord(0x41, 0xabcd)
+	m.SetBytes(0x41, []byte{3, 4})
+
+	before := m.DynamicMemoryAtCheckpoint()
+	testassert.Same(t, 0x60, len(before))
+	testassert.Same(t, 1, before[0x40])
+	testassert.Same(t, 0, before[0x41])
+	testassert.Same(t, 0, before[0x42])
+	testassert.Same(t, 2, m.ReadByte(0x40)) // Memory itself is untouched
+	testassert.Same(t, 0x0304, m.ReadWord(0x41))
+
+	m.Checkpoint()
+	testassert.Same(t, 2, m.DynamicMemoryAtCheckpoint()[0x40])
+}
+
+func TestMemory_CalculateChecksum(t *testing.T) {
+	story := make([]byte, 0x50)
+	story[0] = 3
+	story[0x1b] = 0x24 // The file length in words, which leaves the last 8 bytes out
+	for i := 0x40; i < len(story); i++ {
+		story[i] = 0xff
+	}
+	m, err := NewMemoryFromBytes(story, func(m *Memory) {})
+	testassert.NoError(t, err)
+
+	// Only the story as loaded counts, not what's been written since
+	m.WriteByte(0x40, 0)
+	testassert.Same(t, 8*0xff, m.CalculateChecksum())
+}
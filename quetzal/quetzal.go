@@ -0,0 +1,294 @@
+// Package quetzal reads and writes saved games in the Quetzal format, the standard portable save
+// file format for Z-Machine interpreters.
+package quetzal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type word = uint16
+
+// Header identifies the story a game was saved from, along with where execution resumes
+type Header struct {
+	Release  word
+	Serial   [6]byte
+	Checksum word
+	PC       uint32 // Only the low 24 bits are stored
+}
+
+// Frame is a single routine call on the stack. The bottom frame of a V1-5 save is a dummy frame
+// with no locals, which holds the evaluation stack of the main routine.
+type Frame struct {
+	ReturnPC       uint32
+	DiscardResult  bool
+	ResultVariable uint8
+	ArgCount       int // Number of arguments supplied, at most 7
+	Locals         []word
+	Stack          []word
+}
+
+// Save is a complete saved game. Memory is the story's whole dynamic memory, uncompressed.
+type Save struct {
+	Header Header
+	Memory []byte
+	Frames []Frame
+}
+
+const (
+	maxLocals = 15
+	maxArgs   = 7
+)
+
+// Write encodes the save as an IFZS form. Memory is compressed against the original dynamic
+// memory of the story file, which must be the same length.
+func (s Save) Write(w io.Writer, original []byte) error {
+	if len(original) != len(s.Memory) {
+		return errors.New("Original memory is not the same length as saved memory")
+	}
+
+	stks, err := encodeStacks(s.Frames)
+	if err != nil {
+		return err
+	}
+
+	form := bytes.Buffer{}
+	form.WriteString("IFZS")
+	writeChunk(&form, "IFhd", encodeHeader(s.Header))
+	writeChunk(&form, "CMem", compressMemory(s.Memory, original))
+	writeChunk(&form, "Stks", stks)
+
+	out := bytes.Buffer{}
+	writeChunk(&out, "FORM", form.Bytes())
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// Read decodes an IFZS form. The original dynamic memory of the story file is needed to
+// decompress memory saved in a CMem chunk.
+func Read(r io.Reader, original []byte) (Save, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Save{}, err
+	}
+
+	id, form, _, err := readChunk(data)
+	if err != nil {
+		return Save{}, err
+	}
+	if id != "FORM" || len(form) < 4 || string(form[:4]) != "IFZS" {
+		return Save{}, errors.New("Not a Quetzal save file")
+	}
+
+	save := Save{}
+	found := map[string]bool{}
+	for chunks := form[4:]; len(chunks) > 0; {
+		var chunk []byte
+		id, chunk, chunks, err = readChunk(chunks)
+		if err != nil {
+			return Save{}, err
+		}
+
+		switch id {
+		case "IFhd":
+			save.Header, err = decodeHeader(chunk)
+		case "CMem":
+			save.Memory, err = decompressMemory(chunk, original)
+		case "UMem":
+			save.Memory = bytes.Clone(chunk)
+		case "Stks":
+			save.Frames, err = decodeStacks(chunk)
+		}
+		if err != nil {
+			return Save{}, err
+		}
+		found[id] = true
+	}
+
+	switch {
+	case !found["IFhd"]:
+		return Save{}, errors.New("Save file has no IFhd chunk")
+	case !found["CMem"] && !found["UMem"]:
+		return Save{}, errors.New("Save file has no memory chunk")
+	case !found["Stks"]:
+		return Save{}, errors.New("Save file has no Stks chunk")
+	case len(save.Memory) != len(original):
+		return Save{}, errors.New("Saved memory is not the same length as the story's dynamic memory")
+	}
+
+	return save, nil
+}
+
+func writeChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 != 0 {
+		// Chunks are padded to an even length, but the padding isn't included in the length
+		w.WriteByte(0)
+	}
+}
+
+// readChunk splits the first chunk off data, returning its id, contents and the data after it
+func readChunk(data []byte) (string, []byte, []byte, error) {
+	if len(data) < 8 {
+		return "", nil, nil, errors.New("Truncated chunk header")
+	}
+
+	id := string(data[:4])
+	length := int(binary.BigEndian.Uint32(data[4:8]))
+	data = data[8:]
+	if length > len(data) {
+		return "", nil, nil, fmt.Errorf("Truncated %s chunk", id)
+	}
+
+	chunk := data[:length]
+	rest := data[min(length+length%2, len(data)):]
+	return id, chunk, rest, nil
+}
+
+func encodeHeader(h Header) []byte {
+	data := make([]byte, 0, 13)
+	data = binary.BigEndian.AppendUint16(data, h.Release)
+	data = append(data, h.Serial[:]...)
+	data = binary.BigEndian.AppendUint16(data, h.Checksum)
+	return append(data, byte(h.PC>>16), byte(h.PC>>8), byte(h.PC))
+}
+
+func decodeHeader(data []byte) (Header, error) {
+	if len(data) < 13 {
+		return Header{}, errors.New("IFhd chunk too short")
+	}
+
+	h := Header{
+		Release:  binary.BigEndian.Uint16(data[0:2]),
+		Checksum: binary.BigEndian.Uint16(data[8:10]),
+		PC:       uint32(data[10])<<16 | uint32(data[11])<<8 | uint32(data[12]),
+	}
+	copy(h.Serial[:], data[2:8])
+	return h, nil
+}
+
+// compressMemory XORs memory with the original and run-length encodes the zero bytes, which are
+// written as a zero followed by the number of additional zeros
+func compressMemory(memory []byte, original []byte) []byte {
+	data := make([]byte, 0, len(memory)/4)
+
+	zeros := 0
+	flushZeros := func() {
+		for zeros > 0 {
+			run := min(zeros, 256)
+			data = append(data, 0, byte(run-1))
+			zeros -= run
+		}
+	}
+
+	for i, b := range memory {
+		diff := b ^ original[i]
+		if diff == 0 {
+			zeros++
+			continue
+		}
+		flushZeros()
+		data = append(data, diff)
+	}
+
+	// Trailing zeros are implied, so they are never written
+	return data
+}
+
+func decompressMemory(data []byte, original []byte) ([]byte, error) {
+	memory := bytes.Clone(original)
+
+	i := 0
+	for j := 0; j < len(data); j++ {
+		if data[j] != 0 {
+			if i >= len(memory) {
+				return nil, errors.New("CMem chunk is longer than dynamic memory")
+			}
+			memory[i] ^= data[j]
+			i++
+			continue
+		}
+
+		j++
+		if j >= len(data) {
+			return nil, errors.New("CMem chunk ends in the middle of a run")
+		}
+		i += int(data[j]) + 1
+		if i > len(memory) {
+			return nil, errors.New("CMem chunk is longer than dynamic memory")
+		}
+	}
+
+	return memory, nil
+}
+
+func encodeStacks(frames []Frame) ([]byte, error) {
+	data := make([]byte, 0)
+	for _, f := range frames {
+		if len(f.Locals) > maxLocals {
+			return nil, errors.New("Frame has too many locals to save")
+		}
+
+		flags := byte(len(f.Locals))
+		if f.DiscardResult {
+			flags |= 0x10
+		}
+
+		data = append(data, byte(f.ReturnPC>>16), byte(f.ReturnPC>>8), byte(f.ReturnPC))
+		data = append(data, flags, f.ResultVariable, byte(1<<min(f.ArgCount, maxArgs)-1))
+		data = binary.BigEndian.AppendUint16(data, word(len(f.Stack)))
+		for _, local := range f.Locals {
+			data = binary.BigEndian.AppendUint16(data, local)
+		}
+		for _, value := range f.Stack {
+			data = binary.BigEndian.AppendUint16(data, value)
+		}
+	}
+
+	return data, nil
+}
+
+func decodeStacks(data []byte) ([]Frame, error) {
+	frames := make([]Frame, 0)
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("Truncated stack frame")
+		}
+
+		f := Frame{
+			ReturnPC:       uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]),
+			DiscardResult:  data[3]&0x10 != 0,
+			ResultVariable: data[4],
+		}
+		for args := data[5]; args&1 != 0; args >>= 1 {
+			f.ArgCount++
+		}
+
+		localCount := int(data[3] & 0x0f)
+		stackCount := int(binary.BigEndian.Uint16(data[6:8]))
+		data = data[8:]
+		if len(data) < 2*(localCount+stackCount) {
+			return nil, errors.New("Truncated stack frame")
+		}
+
+		f.Locals = make([]word, 0, localCount)
+		for range localCount {
+			f.Locals = append(f.Locals, binary.BigEndian.Uint16(data))
+			data = data[2:]
+		}
+		f.Stack = make([]word, 0, stackCount)
+		for range stackCount {
+			f.Stack = append(f.Stack, binary.BigEndian.Uint16(data))
+			data = data[2:]
+		}
+
+		frames = append(frames, f)
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,67 @@
+package quetzal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func TestSave_RoundTrip(t *testing.T) {
+	original := make([]byte, 600)
+	for i := range original {
+		original[i] = byte(i * 7)
+	}
+
+	memory := bytes.Clone(original)
+	memory[3] = 0xff
+	memory[4] = 0x00
+	memory[500] ^= 0x01
+
+	save := Save{
+		Header: Header{Release: 88, Serial: [6]byte{'8', '4', '0', '7', '2', '6'}, Checksum: 0xa129, PC: 0x12345},
+		Memory: memory,
+		Frames: []Frame{
+			{Stack: []word{1, 2}, Locals: []word{}},
+			{ReturnPC: 0x4f05, ResultVariable: 0x10, ArgCount: 2, Locals: []word{5, 6, 7}, Stack: []word{}},
+			{ReturnPC: 0x5000, DiscardResult: true, Locals: []word{}, Stack: []word{9}},
+		},
+	}
+
+	buffer := bytes.Buffer{}
+	testassert.NoError(t, save.Write(&buffer, original))
+
+	loaded, err := Read(&buffer, original)
+	testassert.NoError(t, err)
+	testassert.Same(t, save.Header, loaded.Header)
+	testassert.True(t, bytes.Equal(save.Memory, loaded.Memory))
+	testassert.Same(t, len(save.Frames), len(loaded.Frames))
+	for i, f := range save.Frames {
+		testassert.Same(t, f.ReturnPC, loaded.Frames[i].ReturnPC)
+		testassert.Same(t, f.DiscardResult, loaded.Frames[i].DiscardResult)
+		testassert.Same(t, f.ResultVariable, loaded.Frames[i].ResultVariable)
+		testassert.Same(t, f.ArgCount, loaded.Frames[i].ArgCount)
+		testassert.Same(t, len(f.Locals), len(loaded.Frames[i].Locals))
+		testassert.Same(t, len(f.Stack), len(loaded.Frames[i].Stack))
+	}
+}
+
+func TestCompressMemory(t *testing.T) {
+	original := make([]byte, 300)
+	memory := bytes.Clone(original)
+	memory[0] = 0x11
+	memory[290] = 0x22
+
+	compressed := compressMemory(memory, original)
+	// The 289 unchanged bytes in between take two runs, and trailing zeros are left out
+	testassert.True(t, bytes.Equal([]byte{0x11, 0, 255, 0, 32, 0x22}, compressed))
+
+	decompressed, err := decompressMemory(compressed, original)
+	testassert.NoError(t, err)
+	testassert.True(t, bytes.Equal(memory, decompressed))
+}
+
+func TestRead_RejectsOtherForms(t *testing.T) {
+	_, err := Read(bytes.NewReader([]byte("FORM\x00\x00\x00\x04AIFF")), nil)
+	testassert.ErrorMessage(t, "Not a Quetzal save file", err)
+}
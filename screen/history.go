@@ -0,0 +1,189 @@
+package screen
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+const maxHistoryLines = 1000
+
+// history keeps the lower window's text as logical lines, split only at hard new-lines, so it
+// can be wrapped to whatever width it's displayed at
+type history struct {
+	lines   [][]cell
+	current []cell
+}
+
+func (h *history) append(c cell) {
+	h.current = append(h.current, c)
+}
+
+func (h *history) newLine() {
+	h.lines = append(h.lines, h.current)
+	h.current = nil
+
+	if len(h.lines) > maxHistoryLines {
+		h.lines = h.lines[len(h.lines)-maxHistoryLines:]
+	}
+}
+
+// erase removes the last cell of the current line, as when a typed character is deleted
+func (h *history) erase() {
+	if len(h.current) > 0 {
+		h.current = h.current[:len(h.current)-1]
+	}
+}
+
+// rows wraps every line, including the one still being printed, to the given width
+func (h *history) rows(width int) [][]cell {
+	rows := make([][]cell, 0, len(h.lines)+1)
+	for _, line := range h.lines {
+		rows = append(rows, wrapCells(line, width)...)
+	}
+	return append(rows, wrapCells(h.current, width)...)
+}
+
+// wrapCells breaks a line between words using the same rules as buffered printing
+func wrapCells(line []cell, width int) [][]cell {
+	rows := make([][]cell, 0, 1)
+	row := make([]cell, 0, width)
+	word := make([]cell, 0)
+
+	breakRow := func() {
+		rows = append(rows, row)
+		row = make([]cell, 0, width)
+	}
+
+	flushWord := func() {
+		if len(row) > 0 && len(row)+len(word) > width && len(word) <= width {
+			breakRow()
+		}
+		for _, c := range word {
+			if len(row) >= width {
+				breakRow()
+			}
+			row = append(row, c)
+		}
+		word = word[:0]
+	}
+
+	for _, c := range line {
+		if c.r != ' ' {
+			word = append(word, c)
+			continue
+		}
+
+		flushWord()
+		if len(row) >= width {
+			// A space that would wrap is swallowed rather than starting the next line
+			breakRow()
+			continue
+		}
+		row = append(row, c)
+	}
+	flushWord()
+
+	return append(rows, row)
+}
+
+// handleScrollback pages through the history in response to PgUp/PgDn and, when the mouse is
+// captured, the mouse wheel. It reports whether the event was consumed. Any other key returns to the live view first.
+func (s *Screen) handleScrollback(ev tcell.Event) bool {
+	page := max(s.lowerHeight()-1, 1)
+
+	switch event := ev.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyPgUp:
+			s.scrollHistory(page)
+			return true
+		case tcell.KeyPgDn:
+			s.scrollHistory(-page)
+			return true
+		}
+		s.scrollHistory(-s.scrollback)
+		return false
+	case *tcell.EventMouse:
+		switch event.Buttons() {
+		case tcell.WheelUp:
+			s.scrollHistory(3)
+			return true
+		case tcell.WheelDown:
+			s.scrollHistory(-3)
+			return true
+		}
+	}
+
+	return false
+}
+
+// scrollHistory moves the view by a number of rows, where positive values move back in time
+func (s *Screen) scrollHistory(rows int) {
+	width, height := s.screen.Size()
+	wrapped := s.history.rows(width)
+
+	// The bottom row is taken by the indicator while viewing history
+	visibleRows := max(s.lowerHeight()-1, 1)
+	offset := min(max(s.scrollback+rows, 0), max(len(wrapped)-visibleRows, 0))
+	if offset == s.scrollback {
+		return
+	}
+
+	if s.scrollback == 0 {
+		s.live = s.captureLower()
+	}
+	s.scrollback = offset
+
+	if offset == 0 {
+		s.drawLower(s.live)
+		s.live = nil
+		s.screen.Show()
+		return
+	}
+
+	end := len(wrapped) - offset
+	visible := wrapped[max(end-visibleRows, 0):end]
+	s.drawLower(append(visible, nil))
+
+	const indicator = "-- Scrollback: PgUp/PgDn --"
+	style := tcell.StyleDefault.Reverse(true)
+	for x := range width {
+		r := ' '
+		if x < len(indicator) {
+			r = rune(indicator[x])
+		}
+		s.screen.SetContent(x, height-1, r, nil, style)
+	}
+	s.screen.Show()
+}
+
+// drawLower fills the lower window with rows, aligned to the bottom of the screen
+func (s *Screen) drawLower(rows [][]cell) {
+	width, height := s.screen.Size()
+	top := height - len(rows)
+
+	for y := s.upperHeight; y < height; y++ {
+		for x := range width {
+			c := cell{' ', s.blankStyle()}
+			if y >= top && x < len(rows[y-top]) {
+				c = rows[y-top][x]
+			}
+			s.screen.SetContent(x, y, c.r, nil, c.style)
+		}
+	}
+}
+
+func (s *Screen) captureLower() [][]cell {
+	width, height := s.screen.Size()
+	rows := make([][]cell, 0, height-s.upperHeight)
+
+	for y := s.upperHeight; y < height; y++ {
+		row := make([]cell, 0, width)
+		for x := range width {
+			r, _, style, _ := s.screen.GetContent(x, y)
+			row = append(row, cell{r, style})
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
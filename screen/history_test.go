@@ -0,0 +1,106 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/gdamore/tcell/v2"
+)
+
+func cellText(cells []cell) string {
+	runes := make([]rune, 0, len(cells))
+	for _, c := range cells {
+		runes = append(runes, c.r)
+	}
+	return string(runes)
+}
+
+func TestWrapCells(t *testing.T) {
+	type spec struct {
+		text     string
+		width    int
+		expected []string
+	}
+
+	tests := map[string]spec{
+		"fits":          {text: "go north", width: 10, expected: []string{"go north"}},
+		"breaks words":  {text: "the quick brown fox", width: 10, expected: []string{"the quick ", "brown fox"}},
+		"swallows wrap": {text: "abcde fgh", width: 5, expected: []string{"abcde", "fgh"}},
+		"splits long":   {text: "a abcdefgh", width: 5, expected: []string{"a abc", "defgh"}},
+		"empty":         {text: "", width: 5, expected: []string{""}},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			line := make([]cell, 0, len(s.text))
+			for _, r := range s.text {
+				line = append(line, cell{r, tcell.StyleDefault})
+			}
+
+			rows := wrapCells(line, s.width)
+			testassert.Same(t, len(s.expected), len(rows))
+			for i, row := range rows {
+				testassert.Same(t, s.expected[i], cellText(row))
+			}
+		})
+	}
+}
+
+func TestScrollback_PagesAndReturnsToLiveView(t *testing.T) {
+	s := newTestScreen(t, 10, 4)
+	s.Paging = false
+
+	s.PrintText("one\ntwo\nthree\nfour\nfive\n")
+	s.Flush()
+	testassert.Same(t, "five", rowText(s, 2))
+
+	handled := s.handleScrollback(tcell.NewEventKey(tcell.KeyPgUp, 0, tcell.ModNone))
+	testassert.True(t, handled)
+	testassert.Same(t, "one", rowText(s, 0))
+	testassert.Same(t, "two", rowText(s, 1))
+	testassert.Same(t, "three", rowText(s, 2))
+
+	handled = s.handleScrollback(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+	testassert.False(t, handled)
+	testassert.Same(t, 0, s.scrollback)
+	testassert.Same(t, "four", rowText(s, 1))
+	testassert.Same(t, "five", rowText(s, 2))
+}
+
+func TestScrollback_OnlyConsumesWheel(t *testing.T) {
+	s := newTestScreen(t, 10, 4)
+	s.Paging = false
+
+	s.PrintText("one\ntwo\nthree\nfour\nfive\n")
+	s.Flush()
+
+	handled := s.handleScrollback(tcell.NewEventMouse(0, 0, tcell.Button1, tcell.ModNone))
+	testassert.False(t, handled)
+	testassert.Same(t, 0, s.scrollback)
+
+	handled = s.handleScrollback(tcell.NewEventMouse(0, 0, tcell.WheelUp, tcell.ModNone))
+	testassert.True(t, handled)
+	testassert.Same(t, 3, s.scrollback)
+}
+
+func TestResize_ReflowsToNewWidth(t *testing.T) {
+	s := newTestScreen(t, 10, 4)
+	s.Paging = false
+
+	var resized [2]int
+	s.OnResize = func(width int, height int) { resized = [2]int{width, height} }
+
+	s.PrintText("the quick brown\n>")
+	s.Flush()
+	testassert.Same(t, "the quick", rowText(s, 1))
+	testassert.Same(t, "brown", rowText(s, 2))
+
+	s.screen.(tcell.SimulationScreen).SetSize(20, 3)
+	s.resize()
+
+	testassert.Same(t, [2]int{20, 3}, resized)
+	testassert.Same(t, "the quick brown", rowText(s, 1))
+	testassert.Same(t, ">", rowText(s, 2))
+	testassert.Same(t, 1, s.cursorX)
+	testassert.Same(t, 2, s.cursorY)
+}
@@ -0,0 +1,199 @@
+package screen
+
+import (
+	"io"
+	"time"
+
+	"github.com/Drakmyth/golang-zmachine/zstring"
+	"github.com/gdamore/tcell/v2"
+)
+
+type ReadOptions struct {
+	// Accept reports whether a typed rune may be entered. All runes are accepted when nil.
+	Accept func(r rune) bool
+	// Preload is text left over from a previous input, which is displayed and can be edited
+	Preload string
+	// MaxLength is the most runes that can be typed, or 0 for no limit
+	MaxLength int
+	// Complete returns the index the word before the cursor starts at and the words it could be
+	// completed to. Tab does nothing when nil.
+	Complete func(text string) (int, []string)
+	// IsTerminator reports whether a function key ends input. Enter always ends input.
+	IsTerminator func(zc zstring.ZSCII) bool
+	// Interval between calls to OnInterrupt while waiting for input, or 0 for untimed input
+	Interval time.Duration
+	// OnInterrupt reports whether input should end early, in which case the terminator is 0
+	OnInterrupt func() bool
+}
+
+// Read collects a line of input, returning it along with the character that terminated it. The
+// line can be edited as it's typed, and previous lines recalled with the up and down keys.
+// Esc or Ctrl-C return ErrQuit.
+func (s *Screen) Read(options ReadOptions) (string, zstring.ZSCII, error) {
+	if s.quitting {
+		return "", zstring.ZSCII_Null, ErrQuit
+	}
+	s.flushWord()
+	s.linesSinceInput = 0
+
+	s.editor = s.newLineEditor(options)
+	defer func() {
+		s.editor = nil
+		s.screen.HideCursor()
+	}()
+	s.editor.render(s)
+
+	ticks, stop := startTicks(options)
+	defer stop()
+
+	for {
+		ev, interrupted := s.waitForEvent(options, ticks)
+		text := s.editor.text
+		if interrupted {
+			return string(text), zstring.ZSCII_Null, nil
+		}
+
+		key, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+
+		switch key.Key() {
+		case tcell.KeyEscape, tcell.KeyCtrlC:
+			s.quitting = true
+			return "", zstring.ZSCII_Null, ErrQuit
+		case tcell.KeyEnter:
+			s.remember(text)
+			if s.Transcript != nil {
+				io.WriteString(s.Transcript, string(text))
+			}
+			return string(text), zstring.ZSCII_NewLine, nil
+		}
+
+		// Function keys the story asked to end input take priority over editing
+		zc, ok := functionKeyZSCII(key)
+		if ok && options.IsTerminator != nil && options.IsTerminator(zc) {
+			return string(text), zc, nil
+		}
+
+		if s.editor.handleKey(s, key) {
+			s.editor.render(s)
+		}
+	}
+}
+
+// ReadChar waits for a single keypress. Character keys are returned as a rune, while special
+// keys are returned as their ZSCII input code. Both are empty if a timed interrupt ended input.
+// Ctrl-C returns ErrQuit.
+func (s *Screen) ReadChar(options ReadOptions) (rune, zstring.ZSCII, error) {
+	if s.quitting {
+		return 0, zstring.ZSCII_Null, ErrQuit
+	}
+	s.Flush()
+	s.linesSinceInput = 0
+
+	ticks, stop := startTicks(options)
+	defer stop()
+
+	for {
+		ev, interrupted := s.waitForEvent(options, ticks)
+		if interrupted {
+			return 0, zstring.ZSCII_Null, nil
+		}
+
+		key, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+
+		switch key.Key() {
+		case tcell.KeyCtrlC:
+			s.quitting = true
+			return 0, zstring.ZSCII_Null, ErrQuit
+		case tcell.KeyRune:
+			r := key.Rune()
+			if options.Accept == nil || options.Accept(r) {
+				return r, zstring.ZSCII_Null, nil
+			}
+		default:
+			if zc, ok := keyZSCII(key); ok {
+				return 0, zc, nil
+			}
+		}
+	}
+}
+
+// startTicks starts the timer for timed input. A nil channel never fires, so untimed
+// input simply waits on events.
+func startTicks(options ReadOptions) (<-chan time.Time, func()) {
+	if options.Interval <= 0 || options.OnInterrupt == nil {
+		return nil, func() {}
+	}
+
+	ticker := time.NewTicker(options.Interval)
+	return ticker.C, ticker.Stop
+}
+
+// waitForEvent blocks until the next event, calling the interrupt on every tick in the meantime.
+// It reports true instead of an event when the interrupt asks for input to end.
+func (s *Screen) waitForEvent(options ReadOptions, ticks <-chan time.Time) (tcell.Event, bool) {
+	for {
+		s.waiting(true)
+		select {
+		case ev := <-s.Events:
+			s.waiting(false)
+			if _, ok := ev.(*tcell.EventResize); ok {
+				s.resize()
+				continue
+			}
+			if s.handleScrollback(ev) {
+				continue
+			}
+			return ev, false
+		case <-ticks:
+			s.waiting(false)
+			printed := s.printed
+			if s.editor != nil {
+				s.editor.detach(s)
+			}
+			if options.OnInterrupt() {
+				return nil, true
+			}
+			s.flushWord()
+			if s.editor != nil {
+				if s.printed != printed {
+					// The interrupt printed after the input, so show it again from there
+					s.editor.anchor(s)
+				}
+				s.editor.render(s)
+			}
+			s.screen.Show()
+		}
+	}
+}
+
+// waitForKey blocks until any key is pressed. Ctrl-C marks the screen as quitting, which the
+// story finds out about when it next asks for input.
+func (s *Screen) waitForKey() {
+	for {
+		s.waiting(true)
+		ev := <-s.Events
+		s.waiting(false)
+		if _, ok := ev.(*tcell.EventResize); ok {
+			s.resize()
+			continue
+		}
+		if key, ok := ev.(*tcell.EventKey); ok {
+			if key.Key() == tcell.KeyCtrlC {
+				s.quitting = true
+			}
+			return
+		}
+	}
+}
+
+func (s *Screen) waiting(waiting bool) {
+	if s.OnWait != nil {
+		s.OnWait(waiting)
+	}
+}
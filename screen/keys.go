@@ -0,0 +1,51 @@
+package screen
+
+import (
+	"github.com/Drakmyth/golang-zmachine/zstring"
+	"github.com/gdamore/tcell/v2"
+)
+
+var functionKeys = map[tcell.Key]zstring.ZSCII{
+	tcell.KeyUp:    zstring.ZSCII_CursorUp,
+	tcell.KeyDown:  zstring.ZSCII_CursorDown,
+	tcell.KeyLeft:  zstring.ZSCII_CursorLeft,
+	tcell.KeyRight: zstring.ZSCII_CursorRight,
+	tcell.KeyF1:    zstring.ZSCII_F1,
+	tcell.KeyF2:    zstring.ZSCII_F1 + 1,
+	tcell.KeyF3:    zstring.ZSCII_F1 + 2,
+	tcell.KeyF4:    zstring.ZSCII_F1 + 3,
+	tcell.KeyF5:    zstring.ZSCII_F1 + 4,
+	tcell.KeyF6:    zstring.ZSCII_F1 + 5,
+	tcell.KeyF7:    zstring.ZSCII_F1 + 6,
+	tcell.KeyF8:    zstring.ZSCII_F1 + 7,
+	tcell.KeyF9:    zstring.ZSCII_F1 + 8,
+	tcell.KeyF10:   zstring.ZSCII_F1 + 9,
+	tcell.KeyF11:   zstring.ZSCII_F1 + 10,
+	tcell.KeyF12:   zstring.ZSCII_F1 + 11,
+
+	// Terminals only report the keypad keys that don't double as digits or arrows
+	tcell.KeyDownLeft:  zstring.ZSCII_Keypad0 + 1,
+	tcell.KeyDownRight: zstring.ZSCII_Keypad0 + 3,
+	tcell.KeyCenter:    zstring.ZSCII_Keypad0 + 5,
+	tcell.KeyUpLeft:    zstring.ZSCII_Keypad0 + 7,
+	tcell.KeyUpRight:   zstring.ZSCII_Keypad0 + 9,
+}
+
+func functionKeyZSCII(ev *tcell.EventKey) (zstring.ZSCII, bool) {
+	zc, ok := functionKeys[ev.Key()]
+	return zc, ok
+}
+
+// keyZSCII maps the non-character keys accepted by read_char to their ZSCII input codes
+func keyZSCII(ev *tcell.EventKey) (zstring.ZSCII, bool) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		return zstring.ZSCII_NewLine, true
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyDelete:
+		return zstring.ZSCII_Delete, true
+	case tcell.KeyEscape:
+		return zstring.ZSCII_Escape, true
+	}
+
+	return functionKeyZSCII(ev)
+}
@@ -0,0 +1,204 @@
+package screen
+
+import (
+	"io"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type cell struct {
+	r     rune
+	style tcell.Style
+}
+
+// PrintText writes text to the current window. Lower window text is buffered a word at a time
+// so lines break between words, unless buffering has been turned off.
+func (s *Screen) PrintText(text string) {
+	s.printed += len(text)
+	style := s.currentStyle()
+
+	if s.Transcript != nil && s.window == W_Lower {
+		io.WriteString(s.Transcript, text)
+	}
+
+	for _, r := range text {
+		if s.window == W_Upper || !s.Wordwrap {
+			s.putCell(cell{r, style})
+			continue
+		}
+
+		switch r {
+		case ' ', '\n', '\t':
+			s.flushWord()
+			s.putCell(cell{r, style})
+		default:
+			s.word = append(s.word, cell{r, style})
+		}
+	}
+}
+
+// SetBuffering controls whether lower window text is buffered to wrap on word boundaries
+func (s *Screen) SetBuffering(buffered bool) {
+	s.flushWord()
+	s.Wordwrap = buffered
+}
+
+// Flush writes out any partially buffered word
+func (s *Screen) Flush() {
+	s.flushWord()
+	s.screen.Show()
+}
+
+func (s *Screen) flushWord() {
+	if len(s.word) == 0 {
+		return
+	}
+
+	// Words longer than a whole line have no choice but to be split
+	width, _ := s.screen.Size()
+	if s.cursorX > 0 && s.cursorX+len(s.word) > width && len(s.word) <= width {
+		s.wrapLine()
+	}
+
+	for _, c := range s.word {
+		s.putCell(c)
+	}
+	s.word = s.word[:0]
+}
+
+func (s *Screen) putCell(c cell) {
+	width, _ := s.screen.Size()
+
+	switch c.r {
+	case '\n':
+		s.newLine()
+		return
+	case '\t':
+		// Tabs advance to the next tab stop rather than printing a glyph
+		stop := min(width, (s.cursorX/tabWidth+1)*tabWidth)
+		for s.window == W_Lower && s.cursorX < stop {
+			s.history.append(cell{' ', c.style})
+			s.cursorX++
+		}
+		s.cursorX = stop
+		return
+	}
+
+	if s.cursorX >= width {
+		if s.window == W_Upper {
+			// The upper window never wraps, so anything past the edge is lost
+			return
+		}
+
+		s.wrapLine()
+		if c.r == ' ' {
+			// A space that would wrap is swallowed rather than starting the next line
+			return
+		}
+	}
+
+	s.screen.SetContent(s.cursorX, s.cursorY, c.r, nil, c.style)
+	s.cursorX++
+
+	if s.window == W_Lower {
+		s.history.append(c)
+	}
+}
+
+// newLine ends the current line of text
+func (s *Screen) newLine() {
+	if s.window == W_Lower {
+		s.history.newLine()
+	}
+	s.wrapLine()
+}
+
+// wrapLine moves output to the next row without ending the line in history, so it can be
+// wrapped differently when redrawn at another width
+func (s *Screen) wrapLine() {
+	s.cursorX = 0
+	if s.window == W_Upper {
+		s.cursorY++
+		return
+	}
+
+	s.ScrollUp()
+
+	s.linesSinceInput++
+	if s.Paging && !s.quitting && s.linesSinceInput >= s.lowerHeight()-1 {
+		s.waitForMore()
+	}
+}
+
+func (s *Screen) lowerHeight() int {
+	_, height := s.screen.Size()
+	return height - s.upperHeight
+}
+
+// waitForMore pauses output once a full window of text has been printed since the player last
+// had a chance to read it
+func (s *Screen) waitForMore() {
+	const prompt = "[MORE]"
+	_, height := s.screen.Size()
+
+	style := s.currentStyle().Reverse(true)
+	for i, r := range prompt {
+		s.screen.SetContent(i, height-1, r, nil, style)
+	}
+	s.screen.Show()
+
+	s.waitForKey()
+
+	for i := range len(prompt) {
+		s.screen.SetContent(i, height-1, ' ', nil, s.blankStyle())
+	}
+	s.screen.Show()
+	s.linesSinceInput = 0
+}
+
+// ScrollUp scrolls the lower window by one line, leaving the upper window in place
+func (s *Screen) ScrollUp() {
+	width, height := s.screen.Size()
+	for y := s.upperHeight; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if y > s.upperHeight {
+				r, cr, style, _ := s.screen.GetContent(x, y)
+				s.screen.SetContent(x, y-1, r, cr, style)
+			}
+			s.screen.SetContent(x, y, ' ', nil, s.blankStyle())
+		}
+	}
+	s.screen.Show()
+}
+
+// SplitWindow sets the height of the upper window, which takes lines from the top of the screen
+func (s *Screen) SplitWindow(lines int) {
+	s.flushWord()
+
+	_, height := s.screen.Size()
+	s.upperHeight = min(max(lines, 0), height)
+
+	// Keep the lower window cursor out of the upper window
+	if s.window == W_Lower {
+		s.cursorY = max(s.cursorY, s.upperHeight)
+	} else {
+		s.lowerY = max(s.lowerY, s.upperHeight)
+	}
+}
+
+// SetWindow selects the window subsequent text is printed to. Selecting the upper window moves
+// the cursor to its top left corner.
+func (s *Screen) SetWindow(window Window) {
+	s.flushWord()
+	if window == s.window {
+		return
+	}
+
+	if window == W_Upper {
+		s.lowerX, s.lowerY = s.cursorX, s.cursorY
+		s.cursorX, s.cursorY = 0, 0
+	} else {
+		s.cursorX, s.cursorY = s.lowerX, s.lowerY
+	}
+	s.window = window
+}
@@ -0,0 +1,111 @@
+package screen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/gdamore/tcell/v2"
+)
+
+func newTestScreen(t *testing.T, width int, height int) *Screen {
+	t.Helper()
+
+	sim := tcell.NewSimulationScreen("")
+	testassert.NoError(t, sim.Init())
+	sim.SetSize(width, height)
+
+	return newScreen(sim)
+}
+
+func rowText(s *Screen, y int) string {
+	width, _ := s.screen.Size()
+	builder := strings.Builder{}
+	for x := 0; x < width; x++ {
+		r, _, _, _ := s.screen.GetContent(x, y)
+		builder.WriteRune(r)
+	}
+	return strings.TrimRight(builder.String(), " ")
+}
+
+func TestPrintText_WrapsBetweenWords(t *testing.T) {
+	s := newTestScreen(t, 10, 3)
+
+	s.PrintText("the quick brown fox")
+	s.Flush()
+
+	// The lower window starts at the bottom of the screen and scrolls upward
+	testassert.Same(t, "", rowText(s, 0))
+	testassert.Same(t, "the quick", rowText(s, 1))
+	testassert.Same(t, "brown fox", rowText(s, 2))
+}
+
+func TestPrintText_SplitsOverlongWords(t *testing.T) {
+	s := newTestScreen(t, 5, 3)
+
+	s.PrintText("a abcdefgh")
+	s.Flush()
+
+	testassert.Same(t, "a abc", rowText(s, 1))
+	testassert.Same(t, "defgh", rowText(s, 2))
+}
+
+func TestPrintText_Unbuffered(t *testing.T) {
+	s := newTestScreen(t, 10, 3)
+
+	s.SetBuffering(false)
+	s.PrintText("the quick brown")
+
+	testassert.Same(t, "the quick", rowText(s, 1))
+	testassert.Same(t, "brown", rowText(s, 2))
+}
+
+func TestPrintText_UpperWindowClipsAndStaysPut(t *testing.T) {
+	s := newTestScreen(t, 8, 4)
+	s.Paging = false
+
+	s.SplitWindow(1)
+	s.SetWindow(W_Upper)
+	s.PrintText("Status: West of House")
+	s.SetWindow(W_Lower)
+	s.PrintText("one\ntwo\nthree\nfour")
+	s.Flush()
+
+	testassert.Same(t, "Status:", rowText(s, 0))
+	testassert.Same(t, "two", rowText(s, 1))
+	testassert.Same(t, "three", rowText(s, 2))
+	testassert.Same(t, "four", rowText(s, 3))
+}
+
+func TestPrintText_PausesWhenWindowFills(t *testing.T) {
+	s := newTestScreen(t, 8, 5)
+	s.SplitWindow(1)
+	sim := s.screen.(tcell.SimulationScreen)
+
+	// Four lower window lines means a pause after the third scroll
+	s.PrintText("one\ntwo\n")
+	testassert.Same(t, 2, s.linesSinceInput)
+
+	sim.InjectKey(tcell.KeyRune, ' ', tcell.ModNone)
+	s.PrintText("three\nfour\n")
+	s.Flush()
+
+	testassert.Same(t, 1, s.linesSinceInput)
+	testassert.Same(t, "three", rowText(s, 2))
+	testassert.Same(t, "four", rowText(s, 3))
+	testassert.Same(t, "", rowText(s, 4))
+}
+
+func TestPrintText_CtrlCAtMoreStopsPaging(t *testing.T) {
+	s := newTestScreen(t, 8, 5)
+	s.SplitWindow(1)
+	sim := s.screen.(tcell.SimulationScreen)
+
+	sim.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	s.PrintText("one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n")
+	s.Flush()
+
+	// Nothing else waits once the player has asked to quit
+	testassert.True(t, s.quitting)
+	testassert.Same(t, "eight", rowText(s, 3))
+}
@@ -0,0 +1,272 @@
+package screen
+
+import (
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const maxCommandHistory = 100
+
+// lineEditor holds the state of a line of input being typed. The text is drawn in place starting
+// from where the cursor was when input began, wrapping at the edge of the screen.
+type lineEditor struct {
+	text      []rune
+	cursor    int // Index into text the next typed rune is inserted at
+	maxLength int // Longest text allowed, or 0 for no limit
+	accept    func(r rune) bool
+	complete  func(text string) (int, []string)
+	style     tcell.Style
+
+	startX, startY int
+	drawn          int  // Runes drawn by the previous render, which need erasing before the next
+	mark           int  // Length of the current history line before the input
+	recorded       bool // Whether the input is recorded in history, which only the lower window is
+
+	browsing int    // Index into command history being shown, or len(commands) when editing a new line
+	draft    []rune // The new line, kept while browsing history
+
+	completion *completion // Set while Tab is cycling through completions
+}
+
+// completion tracks the candidates for the word being completed. Cycling past the last candidate
+// returns to the word as it was typed.
+type completion struct {
+	start      int
+	typed      []rune
+	candidates []string
+	index      int // Index of the candidate shown, or len(candidates) for the typed word
+}
+
+func (s *Screen) newLineEditor(options ReadOptions) *lineEditor {
+	e := &lineEditor{
+		text:      []rune(options.Preload),
+		maxLength: options.MaxLength,
+		accept:    options.Accept,
+		complete:  options.Complete,
+		style:     s.currentStyle(),
+		browsing:  len(s.commands),
+	}
+	e.text = e.text[:e.limit(len(e.text))]
+	e.cursor = len(e.text)
+	e.anchor(s)
+	return e
+}
+
+// anchor starts the input from the current cursor position, as if nothing had been drawn yet
+func (e *lineEditor) anchor(s *Screen) {
+	e.startX, e.startY = s.cursorX, s.cursorY
+	e.drawn = 0
+	e.recorded = s.window == W_Lower
+	e.mark = len(s.history.current)
+}
+
+// detach takes the input back out of history so other text can be printed after the prompt
+func (e *lineEditor) detach(s *Screen) {
+	if e.recorded {
+		s.history.current = s.history.current[:min(e.mark, len(s.history.current))]
+	}
+}
+
+func (e *lineEditor) limit(length int) int {
+	if e.maxLength <= 0 {
+		return length
+	}
+	return min(length, e.maxLength)
+}
+
+// position is the screen location of the rune at index i of the text
+func (e *lineEditor) position(s *Screen, i int) (int, int) {
+	width, _ := s.screen.Size()
+	offset := e.startX + i
+	return offset % width, e.startY + offset/width
+}
+
+// render redraws the input, scrolling the lower window first if the text no longer fits
+func (e *lineEditor) render(s *Screen) {
+	_, height := s.screen.Size()
+
+	for i := range e.drawn {
+		x, y := e.position(s, i)
+		if y < height {
+			s.screen.SetContent(x, y, ' ', nil, s.blankStyle())
+		}
+	}
+
+	// Leave room for the terminal cursor after the last rune as well. The upper window never
+	// scrolls, so anything past the bottom of the screen there is simply not drawn.
+	if s.window == W_Lower {
+		for _, lastY := e.position(s, len(e.text)); lastY >= height; lastY-- {
+			s.ScrollUp()
+			e.startY--
+		}
+	}
+
+	cells := make([]cell, 0, len(e.text))
+	for i, r := range e.text {
+		x, y := e.position(s, i)
+		if y < height {
+			s.screen.SetContent(x, y, r, nil, e.style)
+		}
+		cells = append(cells, cell{r, e.style})
+	}
+	e.drawn = len(e.text)
+
+	if e.recorded {
+		e.detach(s)
+		s.history.current = append(s.history.current, cells...)
+	}
+
+	// Keep the screen cursor at the end of the text so printing carries on from there
+	s.cursorX, s.cursorY = e.position(s, len(e.text))
+
+	x, y := e.position(s, e.cursor)
+	s.screen.ShowCursor(x, min(y, height-1))
+	s.screen.Show()
+}
+
+func (e *lineEditor) insert(r rune) {
+	if e.accept != nil && !e.accept(r) {
+		return
+	}
+	if e.limit(len(e.text)+1) <= len(e.text) {
+		return
+	}
+
+	e.text = slices.Insert(e.text, e.cursor, r)
+	e.cursor++
+}
+
+// deleteBefore removes the text from index start up to the cursor
+func (e *lineEditor) deleteBefore(start int) {
+	e.text = slices.Delete(e.text, start, e.cursor)
+	e.cursor = start
+}
+
+// wordStart finds where the word before the cursor begins, skipping any spaces in between
+func (e *lineEditor) wordStart() int {
+	i := e.cursor
+	for i > 0 && e.text[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && e.text[i-1] != ' ' {
+		i--
+	}
+	return i
+}
+
+// browse moves through the command history, where negative steps go to older commands
+func (e *lineEditor) browse(commands [][]rune, step int) {
+	index := min(max(e.browsing+step, 0), len(commands))
+	if index == e.browsing {
+		return
+	}
+
+	if e.browsing == len(commands) {
+		e.draft = e.text
+	}
+	e.browsing = index
+
+	text := e.draft
+	if index < len(commands) {
+		text = commands[index]
+	}
+	e.text = slices.Clone(text[:e.limit(len(text))])
+	e.cursor = len(e.text)
+}
+
+// completeWord replaces the word before the cursor with the next completion candidate
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	if e.completion == nil {
+		start, candidates := e.complete(string(e.text[:e.cursor]))
+		if start < 0 || start > e.cursor {
+			return
+		}
+
+		// Leave out anything too long to fit in place of the typed word
+		others := len(e.text) - (e.cursor - start)
+		candidates = slices.DeleteFunc(candidates, func(c string) bool {
+			length := others + len([]rune(c))
+			return e.limit(length) < length
+		})
+		if len(candidates) == 0 {
+			return
+		}
+
+		e.completion = &completion{
+			start:      start,
+			typed:      slices.Clone(e.text[start:e.cursor]),
+			candidates: candidates,
+			index:      len(candidates),
+		}
+	}
+
+	c := e.completion
+	c.index = (c.index + 1) % (len(c.candidates) + 1)
+
+	replacement := c.typed
+	if c.index < len(c.candidates) {
+		replacement = []rune(c.candidates[c.index])
+	}
+
+	e.text = slices.Replace(e.text, c.start, e.cursor, replacement...)
+	e.cursor = c.start + len(replacement)
+}
+
+// handleKey applies an editing key, reporting false for keys the editor doesn't use
+func (e *lineEditor) handleKey(s *Screen, key *tcell.EventKey) bool {
+	if key.Key() != tcell.KeyTab {
+		e.completion = nil
+	}
+
+	switch key.Key() {
+	case tcell.KeyTab:
+		e.completeWord()
+	case tcell.KeyRune:
+		e.insert(key.Rune())
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if e.cursor > 0 {
+			e.deleteBefore(e.cursor - 1)
+		}
+	case tcell.KeyDelete:
+		if e.cursor < len(e.text) {
+			e.text = slices.Delete(e.text, e.cursor, e.cursor+1)
+		}
+	case tcell.KeyLeft:
+		e.cursor = max(e.cursor-1, 0)
+	case tcell.KeyRight:
+		e.cursor = min(e.cursor+1, len(e.text))
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		e.cursor = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		e.cursor = len(e.text)
+	case tcell.KeyCtrlW:
+		e.deleteBefore(e.wordStart())
+	case tcell.KeyCtrlU:
+		e.deleteBefore(0)
+	case tcell.KeyUp:
+		e.browse(s.commands, -1)
+	case tcell.KeyDown:
+		e.browse(s.commands, 1)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// remember adds a finished line to the command history, skipping blanks and repeats
+func (s *Screen) remember(text []rune) {
+	if len(text) == 0 || (len(s.commands) > 0 && slices.Equal(s.commands[len(s.commands)-1], text)) {
+		return
+	}
+
+	s.commands = append(s.commands, slices.Clone(text))
+	if len(s.commands) > maxCommandHistory {
+		s.commands = s.commands[len(s.commands)-maxCommandHistory:]
+	}
+}
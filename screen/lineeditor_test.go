@@ -0,0 +1,160 @@
+package screen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+	"github.com/gdamore/tcell/v2"
+)
+
+type keypress struct {
+	key tcell.Key
+	r   rune
+}
+
+func typeText(text string) []keypress {
+	keys := make([]keypress, 0, len(text))
+	for _, r := range text {
+		keys = append(keys, keypress{tcell.KeyRune, r})
+	}
+	return keys
+}
+
+// inject types keys followed by Enter. The simulation's event queue is small and blocks when
+// full, so keys are sent in the background while input is read.
+func inject(s *Screen, keys ...[]keypress) {
+	sim := s.screen.(tcell.SimulationScreen)
+	go func() {
+		for _, group := range keys {
+			for _, k := range group {
+				sim.InjectKey(k.key, k.r, tcell.ModNone)
+			}
+		}
+		sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	}()
+}
+
+func press(keys ...tcell.Key) []keypress {
+	presses := make([]keypress, 0, len(keys))
+	for _, key := range keys {
+		presses = append(presses, keypress{key, 0})
+	}
+	return presses
+}
+
+func TestRead_Editing(t *testing.T) {
+	type spec struct {
+		keys     [][]keypress
+		options  ReadOptions
+		expected string
+	}
+
+	tests := map[string]spec{
+		"typing":         {keys: [][]keypress{typeText("get lamp")}, expected: "get lamp"},
+		"backspace":      {keys: [][]keypress{typeText("get lampx"), press(tcell.KeyBackspace2)}, expected: "get lamp"},
+		"insert at left": {keys: [][]keypress{typeText("gt"), press(tcell.KeyLeft), typeText("e")}, expected: "get"},
+		"home and end":   {keys: [][]keypress{typeText("et"), press(tcell.KeyHome), typeText("g"), press(tcell.KeyEnd), typeText("!")}, expected: "get!"},
+		"delete":         {keys: [][]keypress{typeText("gxet"), press(tcell.KeyHome, tcell.KeyRight, tcell.KeyDelete)}, expected: "get"},
+		"delete word":    {keys: [][]keypress{typeText("get lamp  "), press(tcell.KeyCtrlW)}, expected: "get "},
+		"delete line":    {keys: [][]keypress{typeText("get lamp"), press(tcell.KeyLeft, tcell.KeyCtrlU)}, expected: "p"},
+		"max length":     {keys: [][]keypress{typeText("get lamp")}, options: ReadOptions{MaxLength: 5}, expected: "get l"},
+		"preload":        {keys: [][]keypress{typeText("lamp")}, options: ReadOptions{Preload: "get "}, expected: "get lamp"},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			scr := newTestScreen(t, 20, 4)
+			inject(scr, s.keys...)
+
+			text, terminator, err := scr.Read(s.options)
+			testassert.NoError(t, err)
+			testassert.Same(t, s.expected, text)
+			testassert.Same(t, zstring.ZSCII_NewLine, terminator)
+			testassert.Same(t, strings.TrimRight(s.expected, " "), rowText(scr, 3))
+		})
+	}
+}
+
+func TestRead_RecallsHistory(t *testing.T) {
+	s := newTestScreen(t, 20, 4)
+
+	inject(s, typeText("north"))
+	s.Read(ReadOptions{})
+	inject(s, typeText("south"))
+	s.Read(ReadOptions{})
+
+	inject(s, typeText("draft"), press(tcell.KeyUp, tcell.KeyUp))
+	text, _, _ := s.Read(ReadOptions{})
+	testassert.Same(t, "north", text)
+
+	inject(s, typeText("draft"), press(tcell.KeyUp, tcell.KeyDown))
+	text, _, _ = s.Read(ReadOptions{})
+	testassert.Same(t, "draft", text)
+}
+
+func TestRead_WrapsLongInput(t *testing.T) {
+	s := newTestScreen(t, 10, 4)
+	s.PrintText(">")
+
+	inject(s, typeText("open the mailbox"))
+	text, _, _ := s.Read(ReadOptions{})
+
+	testassert.Same(t, "open the mailbox", text)
+	testassert.Same(t, ">open the", rowText(s, 2))
+	testassert.Same(t, "mailbox", rowText(s, 3))
+}
+
+func TestRead_CompletesWords(t *testing.T) {
+	complete := func(text string) (int, []string) {
+		start := strings.LastIndex(text, " ") + 1
+		candidates := make([]string, 0)
+		for _, word := range []string{"lamp", "lantern", "leaflet"} {
+			if strings.HasPrefix(word, text[start:]) {
+				candidates = append(candidates, word)
+			}
+		}
+		return start, candidates
+	}
+
+	type spec struct {
+		keys      [][]keypress
+		maxLength int
+		expected  string
+	}
+
+	tests := map[string]spec{
+		"first":          {keys: [][]keypress{typeText("get la"), press(tcell.KeyTab)}, expected: "get lamp"},
+		"cycles":         {keys: [][]keypress{typeText("get la"), press(tcell.KeyTab, tcell.KeyTab)}, expected: "get lantern"},
+		"back to typed":  {keys: [][]keypress{typeText("get la"), press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)}, expected: "get la"},
+		"then typing":    {keys: [][]keypress{typeText("get la"), press(tcell.KeyTab), typeText(" now")}, expected: "get lamp now"},
+		"no candidates":  {keys: [][]keypress{typeText("get x"), press(tcell.KeyTab)}, expected: "get x"},
+		"too long":       {keys: [][]keypress{typeText("get la"), press(tcell.KeyTab, tcell.KeyTab)}, maxLength: 9, expected: "get la"},
+		"restarts cycle": {keys: [][]keypress{typeText("get la"), press(tcell.KeyTab, tcell.KeyBackspace2, tcell.KeyTab)}, expected: "get lamp"},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			scr := newTestScreen(t, 20, 4)
+			inject(scr, s.keys...)
+
+			text, _, _ := scr.Read(ReadOptions{Complete: complete, MaxLength: s.maxLength})
+			testassert.Same(t, s.expected, text)
+		})
+	}
+}
+
+func TestRead_EscapeQuits(t *testing.T) {
+	s := newTestScreen(t, 20, 4)
+
+	inject(s, typeText("nor"), press(tcell.KeyEscape))
+	_, _, err := s.Read(ReadOptions{})
+	testassert.True(t, errors.Is(err, ErrQuit))
+	testassert.True(t, s.quitting)
+
+	// Once quitting, later input gives up straight away
+	_, _, err = s.ReadChar(ReadOptions{})
+	testassert.True(t, errors.Is(err, ErrQuit))
+}
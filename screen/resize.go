@@ -0,0 +1,44 @@
+package screen
+
+// resize redraws the screen after the terminal changes size. The lower window is rebuilt from
+// history wrapped to the new width, so text reflows rather than being cut off or left behind.
+func (s *Screen) resize() {
+	s.screen.Sync()
+	width, height := s.screen.Size()
+
+	// Leave scrollback, since the saved live view was captured at the old size
+	s.scrollback = 0
+	s.live = nil
+
+	s.upperHeight = min(s.upperHeight, height)
+
+	// Input is redrawn separately once the rest of the text is in place
+	if s.editor != nil {
+		s.editor.detach(s)
+	}
+
+	rows := s.history.rows(width)
+	visible := rows[max(len(rows)-s.lowerHeight(), 0):]
+	s.drawLower(visible)
+
+	// The lower window cursor always sits at the end of the last line of text
+	lowerX, lowerY := len(rows[len(rows)-1]), height-1
+	if s.window == W_Lower {
+		s.cursorX, s.cursorY = lowerX, lowerY
+	} else {
+		s.lowerX, s.lowerY = lowerX, lowerY
+		s.cursorX = min(s.cursorX, width)
+		s.cursorY = min(s.cursorY, max(s.upperHeight-1, 0))
+	}
+
+	if s.editor != nil {
+		s.editor.anchor(s)
+		s.editor.render(s)
+	}
+
+	s.screen.Show()
+
+	if s.OnResize != nil {
+		s.OnResize(width, height)
+	}
+}
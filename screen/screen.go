@@ -1,26 +1,77 @@
 package screen
 
 import (
-	"os"
-	"strings"
+	"errors"
+	"io"
 
 	"github.com/Drakmyth/golang-zmachine/assert"
 	"github.com/gdamore/tcell/v2"
 )
 
+const tabWidth = 8
+
+// ErrQuit is returned by input when the player presses Esc or Ctrl-C to leave the story
+var ErrQuit = errors.New("The player quit")
+
+type Window int
+
+const (
+	W_Lower Window = 0
+	W_Upper Window = 1
+)
+
 type Screen struct {
 	screen           tcell.Screen
 	Events           chan tcell.Event
 	QuitEvents       chan struct{}
 	cursorX, cursorY int
+	lowerX, lowerY   int // Lower window cursor, saved while the upper window is selected
+	window           Window
+	upperHeight      int
 	Wordwrap         bool
+	Paging           bool
+	linesSinceInput  int
+	word             []cell
+	history          history
+	scrollback       int      // Rows the lower window is scrolled back into history, 0 when live
+	live             [][]cell // Lower window contents saved while viewing history
+	printed          int
+	textStyle        TextStyle
+	foreground       tcell.Color
+	background       tcell.Color
+	commands         [][]rune                    // Previously entered lines, oldest first
+	editor           *lineEditor                 // The line being typed, while reading input
+	OnResize         func(width int, height int) // Called after the terminal changes size
+	OnWait           func(waiting bool)          // Called when waiting for a key starts and ends
+	quitting         bool
+	Transcript       io.Writer // Receives lower window text and typed input when set
 }
 
 func NewScreen() *Screen {
 	s, err := tcell.NewScreen()
 	assert.NoError(err, "Error initializing screen")
 
-	s.Init()
+	err = s.Init()
+	assert.NoError(err, "Error initializing screen")
+
+	return newScreen(s)
+}
+
+// NewSimulationScreen draws to memory instead of a terminal, so stories can run without one.
+// Keys injected into the returned simulation are read as input.
+func NewSimulationScreen(width int, height int) (*Screen, tcell.SimulationScreen) {
+	sim := tcell.NewSimulationScreen("")
+	err := sim.Init()
+	assert.NoError(err, "Error initializing screen")
+	sim.SetSize(width, height)
+
+	return newScreen(sim), sim
+}
+
+func newScreen(s tcell.Screen) *Screen {
+	foreground, background := colours[defaultForeground], colours[defaultBackground]
+
+	s.SetStyle(tcell.StyleDefault.Foreground(foreground).Background(background))
 	s.Clear()
 	s.Show()
 
@@ -37,68 +88,33 @@ func NewScreen() *Screen {
 		QuitEvents: quit,
 		cursorX:    0,
 		cursorY:    height - 1,
+		window:     W_Lower,
 		Wordwrap:   true,
+		Paging:     true,
+		textStyle:  TS_Roman,
+		foreground: foreground,
+		background: background,
 	}
 }
 
-func (s *Screen) End() {
-	s.screen.Fini()
+func (s *Screen) Size() (int, int) {
+	return s.screen.Size()
 }
 
-func (s *Screen) Read() string {
-	stopReading := false
-	buffer := strings.Builder{}
-
-	for !stopReading {
-		ev := <-s.Events
-		switch eventType := ev.(type) {
-		// TODO: Not sure if this is the right place to handle EventResize
-		// case *tcell.EventResize:
-		// 	_, height := s.screen.Size()
-		// 	s.cursorY = height - 1
-		// 	s.screen.Sync()
-		case *tcell.EventKey:
-			switch eventType.Key() {
-			case tcell.KeyEscape, tcell.KeyCtrlC:
-				// TODO: Replace with call to ZMachine.Shutdown(0)
-				s.screen.Fini()
-				os.Exit(0)
-			case tcell.KeyEnter:
-				// TODO: In V5+ this should check for terminating characters rather than only newline
-				stopReading = true
-			case tcell.KeyRune:
-				buffer.WriteRune(eventType.Rune())
-			}
-		}
+// CaptureMouse lets the mouse wheel scroll through history. Terminals report the wheel along
+// with every click, so capturing it takes away the terminal's own text selection.
+func (s *Screen) CaptureMouse(capture bool) {
+	if capture {
+		s.screen.EnableMouse(tcell.MouseButtonEvents)
+	} else {
+		s.screen.DisableMouse()
 	}
-
-	return buffer.String()
 }
 
-func (s *Screen) PrintText(text string) {
-	width, _ := s.screen.Size()
-
-	for _, r := range text {
-		if r == '\n' || (s.Wordwrap && s.cursorX >= width) {
-			s.ScrollUp()
-			s.cursorX = 0
-			if r == '\n' {
-				continue
-			}
-		}
-		s.screen.SetContent(s.cursorX, s.cursorY, r, []rune{}, tcell.StyleDefault)
-		s.cursorX++
-	}
+func (s *Screen) Beep() {
+	s.screen.Beep()
 }
 
-func (s *Screen) ScrollUp() {
-	width, height := s.screen.Size()
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, cr, style, _ := s.screen.GetContent(x, y)
-			s.screen.SetContent(x, y-1, r, cr, style)
-			s.screen.SetContent(x, y, ' ', nil, tcell.StyleDefault)
-		}
-	}
-	s.screen.Show()
+func (s *Screen) End() {
+	s.screen.Fini()
 }
@@ -0,0 +1,123 @@
+package screen
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+type TextStyle uint8
+
+const (
+	TS_Roman        TextStyle = 0
+	TS_ReverseVideo TextStyle = 1
+	TS_Bold         TextStyle = 2
+	TS_Italic       TextStyle = 4
+	TS_FixedPitch   TextStyle = 8
+)
+
+// Z-Machine colour numbers, as used by set_colour
+const (
+	ZC_Current = 0
+	ZC_Default = 1
+)
+
+var colours = map[int]tcell.Color{
+	2:  tcell.ColorBlack,
+	3:  tcell.ColorMaroon,
+	4:  tcell.ColorGreen,
+	5:  tcell.ColorOlive,
+	6:  tcell.ColorNavy,
+	7:  tcell.ColorPurple,
+	8:  tcell.ColorTeal,
+	9:  tcell.ColorWhite,
+	10: tcell.ColorSilver,
+	11: tcell.ColorGray,
+	12: tcell.NewHexColor(0x404040),
+}
+
+// The screen is painted white on black, so the story can be told its default colours. Colour 1
+// and true colour -1 select these.
+const (
+	defaultForeground = 9
+	defaultBackground = 2
+)
+
+// True colours are 15-bit values, with these values reserved
+const (
+	TC_Current = -2
+	TC_Default = -1
+)
+
+func (s *Screen) ColoursAvailable() bool {
+	return s.screen.Colors() > 1
+}
+
+// DefaultColours are the foreground and background colour numbers the screen is painted with
+func (s *Screen) DefaultColours() (int, int) {
+	return defaultForeground, defaultBackground
+}
+
+// SetTextStyle applies a style. Roman clears all styles, and any other style is combined with
+// those already active.
+func (s *Screen) SetTextStyle(style TextStyle) {
+	if style == TS_Roman {
+		s.textStyle = TS_Roman
+		return
+	}
+	s.textStyle |= style
+}
+
+// SetColour applies foreground and background Z-Machine colour numbers. Unknown colour
+// numbers leave the current colour in place.
+func (s *Screen) SetColour(foreground int, background int) {
+	s.foreground = lookupColour(foreground, s.foreground, colours[defaultForeground])
+	s.background = lookupColour(background, s.background, colours[defaultBackground])
+}
+
+// SetTrueColour applies foreground and background 15-bit colours
+func (s *Screen) SetTrueColour(foreground int, background int) {
+	s.foreground = lookupTrueColour(foreground, s.foreground, colours[defaultForeground])
+	s.background = lookupTrueColour(background, s.background, colours[defaultBackground])
+}
+
+func lookupColour(colour int, current tcell.Color, fallback tcell.Color) tcell.Color {
+	if colour == ZC_Default {
+		return fallback
+	}
+
+	if c, ok := colours[colour]; ok {
+		return c
+	}
+	return current
+}
+
+func lookupTrueColour(colour int, current tcell.Color, fallback tcell.Color) tcell.Color {
+	switch {
+	case colour == TC_Default:
+		return fallback
+	case colour < 0 || colour > 0x7fff:
+		return current
+	}
+
+	// Scale each 5-bit channel to 8 bits, filling the low bits so 0x1f becomes 0xff
+	channel := func(shift int) int32 {
+		c := int32(colour>>shift) & 0b11111
+		return c<<3 | c>>2
+	}
+	return tcell.NewRGBColor(channel(0), channel(5), channel(10))
+}
+
+// currentStyle is the tcell style matching the active text style and colours. Fixed pitch has
+// no effect since terminals are always fixed pitch.
+func (s *Screen) currentStyle() tcell.Style {
+	return tcell.StyleDefault.
+		Foreground(s.foreground).
+		Background(s.background).
+		Reverse(s.textStyle&TS_ReverseVideo != 0).
+		Bold(s.textStyle&TS_Bold != 0).
+		Italic(s.textStyle&TS_Italic != 0)
+}
+
+// blankStyle is used for erased cells, which take the current background colour
+func (s *Screen) blankStyle() tcell.Style {
+	return tcell.StyleDefault.Background(s.background)
+}
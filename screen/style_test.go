@@ -0,0 +1,110 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSetTextStyle_CombinesUntilRoman(t *testing.T) {
+	s := newTestScreen(t, 10, 3)
+
+	s.SetTextStyle(TS_Bold)
+	s.SetTextStyle(TS_Italic)
+	s.SetTextStyle(TS_FixedPitch)
+
+	_, _, attrs := s.currentStyle().Decompose()
+	testassert.Same(t, tcell.AttrBold|tcell.AttrItalic, attrs)
+
+	s.SetTextStyle(TS_Roman)
+	s.SetTextStyle(TS_ReverseVideo)
+
+	_, _, attrs = s.currentStyle().Decompose()
+	testassert.Same(t, tcell.AttrReverse, attrs)
+}
+
+func TestSetColour(t *testing.T) {
+	tests := map[string]struct {
+		foreground         int
+		background         int
+		expectedForeground tcell.Color
+		expectedBackground tcell.Color
+	}{
+		"Colours":       {3, 6, tcell.ColorMaroon, tcell.ColorNavy},
+		"Dark grey":     {12, 10, tcell.NewHexColor(0x404040), tcell.ColorSilver},
+		"Current":       {ZC_Current, ZC_Current, tcell.ColorGreen, tcell.ColorOlive},
+		"Unknown keeps": {13, 255, tcell.ColorGreen, tcell.ColorOlive},
+		"Default":       {ZC_Default, ZC_Default, tcell.ColorWhite, tcell.ColorBlack},
+		"Mixed":         {ZC_Default, 7, tcell.ColorWhite, tcell.ColorPurple},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestScreen(t, 10, 3)
+			s.SetColour(4, 5)
+
+			s.SetColour(test.foreground, test.background)
+
+			fg, bg, _ := s.currentStyle().Decompose()
+			testassert.Same(t, test.expectedForeground, fg)
+			testassert.Same(t, test.expectedBackground, bg)
+		})
+	}
+}
+
+func TestLookupTrueColour(t *testing.T) {
+	current := tcell.ColorGreen
+	fallback := tcell.ColorWhite
+
+	tests := map[string]struct {
+		colour   int
+		expected tcell.Color
+	}{
+		"Black":        {0x0000, tcell.NewRGBColor(0, 0, 0)},
+		"White":        {0x7fff, tcell.NewRGBColor(255, 255, 255)},
+		"Red":          {0x001f, tcell.NewRGBColor(255, 0, 0)},
+		"Green":        {0x03e0, tcell.NewRGBColor(0, 255, 0)},
+		"Blue":         {0x7c00, tcell.NewRGBColor(0, 0, 255)},
+		"Low bits":     {0x0421, tcell.NewRGBColor(8, 8, 8)},
+		"Half":         {0x0010, tcell.NewRGBColor(132, 0, 0)},
+		"Default":      {TC_Default, fallback},
+		"Current":      {TC_Current, current},
+		"Out of range": {0x8000, current},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			testassert.Same(t, test.expected, lookupTrueColour(test.colour, current, fallback))
+		})
+	}
+}
+
+func TestPrintText_PaintsCurrentStyle(t *testing.T) {
+	s := newTestScreen(t, 10, 3)
+	s.SetBuffering(false)
+
+	s.PrintText("a")
+	s.SetColour(3, 8)
+	s.SetTextStyle(TS_Bold)
+	s.PrintText("b")
+
+	_, _, style, _ := s.screen.GetContent(0, 2)
+	fg, bg, attrs := style.Decompose()
+	testassert.Same(t, tcell.ColorWhite, fg)
+	testassert.Same(t, tcell.ColorBlack, bg)
+	testassert.Same(t, tcell.AttrNone, attrs)
+
+	_, _, style, _ = s.screen.GetContent(1, 2)
+	fg, bg, attrs = style.Decompose()
+	testassert.Same(t, tcell.ColorMaroon, fg)
+	testassert.Same(t, tcell.ColorTeal, bg)
+	testassert.Same(t, tcell.AttrBold, attrs)
+
+	// Untouched cells are drawn in the screen's default colours
+	s.screen.Show()
+	cells, _, _ := s.screen.(tcell.SimulationScreen).GetContents()
+	fg, bg, _ = cells[5].Style.Decompose()
+	testassert.Same(t, tcell.ColorWhite, fg)
+	testassert.Same(t, tcell.ColorBlack, bg)
+}
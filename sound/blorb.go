@@ -0,0 +1,133 @@
+// Package sound plays the sound effects requested by the sound_effect opcode. Sampled sounds
+// are read from a Blorb resource file.
+package sound
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Blorb resource types for sounds. AIFF sounds are stored as complete IFF forms.
+const (
+	FormatAIFF = "FORM"
+	FormatOgg  = "OGGV"
+	FormatMOD  = "MOD "
+)
+
+// Resource is a single sound stored in a Blorb file
+type Resource struct {
+	Format string
+	Data   []byte // The whole chunk, including its header
+}
+
+// Blorb holds the sounds from a Blorb resource file
+type Blorb struct {
+	sounds  map[int]Resource
+	repeats map[int]int // Repeat counts from the Loop chunk, used by V3 stories
+}
+
+func ReadBlorb(r io.Reader) (*Blorb, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 12 || string(data[:4]) != "FORM" || string(data[8:12]) != "IFRS" {
+		return nil, errors.New("Not a Blorb resource file")
+	}
+
+	blorb := &Blorb{sounds: map[int]Resource{}, repeats: map[int]int{}}
+	foundIndex := false
+
+	for offset := 12; offset+8 <= len(data); {
+		id, chunk, next, err := readChunk(data, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		switch id {
+		case "RIdx":
+			err = blorb.readIndex(data, chunk)
+			foundIndex = true
+		case "Loop":
+			blorb.readLoops(chunk)
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset = next
+	}
+
+	if !foundIndex {
+		return nil, errors.New("Blorb file has no resource index")
+	}
+	return blorb, nil
+}
+
+// readChunk reads the chunk at offset, returning its id, contents and the offset of the chunk after
+func readChunk(data []byte, offset int) (string, []byte, int, error) {
+	if offset+8 > len(data) {
+		return "", nil, 0, errors.New("Truncated chunk header")
+	}
+
+	id := string(data[offset : offset+4])
+	length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+	start := offset + 8
+	if start+length > len(data) {
+		return "", nil, 0, fmt.Errorf("Truncated %s chunk", id)
+	}
+
+	return id, data[start : start+length], start + length + length%2, nil
+}
+
+func (b *Blorb) readIndex(data []byte, index []byte) error {
+	if len(index) < 4 {
+		return errors.New("Blorb resource index too short")
+	}
+
+	count := int(binary.BigEndian.Uint32(index))
+	entries := index[4:]
+	if len(entries) < count*12 {
+		return errors.New("Blorb resource index too short")
+	}
+
+	for i := range count {
+		entry := entries[i*12 : i*12+12]
+		if string(entry[:4]) != "Snd " {
+			continue
+		}
+
+		number := int(binary.BigEndian.Uint32(entry[4:8]))
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		id, _, next, err := readChunk(data, offset)
+		if err != nil {
+			return err
+		}
+
+		b.sounds[number] = Resource{Format: id, Data: data[offset:next]}
+	}
+
+	return nil
+}
+
+func (b *Blorb) readLoops(chunk []byte) {
+	for i := 0; i+8 <= len(chunk); i += 8 {
+		number := int(binary.BigEndian.Uint32(chunk[i : i+4]))
+		b.repeats[number] = int(binary.BigEndian.Uint32(chunk[i+4 : i+8]))
+	}
+}
+
+func (b *Blorb) Sound(number int) (Resource, bool) {
+	resource, ok := b.sounds[number]
+	return resource, ok
+}
+
+// Repeats is the number of times a sound plays by default, where 0 means forever
+func (b *Blorb) Repeats(number int) int {
+	if repeats, ok := b.repeats[number]; ok {
+		return repeats
+	}
+	return 1
+}
@@ -0,0 +1,159 @@
+package sound
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Volumes run from 1 to MaxVolume, and repeats are either a count or one of these
+const (
+	MaxVolume     = 8
+	RepeatDefault = 0  // Use the count the sound was stored with
+	RepeatForever = -1 // Play until stopped
+)
+
+// LogPlayer has no sampled sounds. It describes each request to Log, if set, and rings Bell for
+// beeps. Sounds finish as soon as they start.
+type LogPlayer struct {
+	Log  io.Writer
+	Bell func()
+}
+
+func (p LogPlayer) logf(format string, args ...any) {
+	if p.Log != nil {
+		fmt.Fprintf(p.Log, format+"\n", args...)
+	}
+}
+
+func (p LogPlayer) Sampled() bool {
+	return false
+}
+
+func (p LogPlayer) Beep(high bool) {
+	p.logf("beep high=%t", high)
+	if p.Bell != nil {
+		p.Bell()
+	}
+}
+
+func (p LogPlayer) Prepare(number int) error {
+	p.logf("prepare %d", number)
+	return nil
+}
+
+func (p LogPlayer) Start(number int, volume int, repeats int, done func()) error {
+	p.logf("start %d volume=%d repeats=%d", number, volume, repeats)
+	done()
+	return nil
+}
+
+func (p LogPlayer) Stop(number int) {
+	p.logf("stop %d", number)
+}
+
+func (p LogPlayer) Finish(number int) {
+	p.logf("finish %d", number)
+}
+
+// FilePlayer plays sounds from a Blorb file by writing each one to a file in a directory, which
+// makes it possible to check sound output without an audio device. AIFF sounds are converted to
+// WAV with the volume and repeats applied. Ogg Vorbis sounds can't be decoded, so they are
+// reported as unsupported. A sound is finished once its file has been written.
+type FilePlayer struct {
+	Bell     func()
+	blorb    *Blorb
+	dir      string
+	prepared map[int]Resource
+	decoded  map[int]aiff
+}
+
+func NewFilePlayer(blorb *Blorb, dir string) *FilePlayer {
+	return &FilePlayer{
+		blorb:    blorb,
+		dir:      dir,
+		prepared: map[int]Resource{},
+		decoded:  map[int]aiff{},
+	}
+}
+
+func (p *FilePlayer) Sampled() bool {
+	return true
+}
+
+func (p *FilePlayer) Beep(high bool) {
+	if p.Bell != nil {
+		p.Bell()
+	}
+}
+
+// Prepare loads and decodes a sound ahead of time
+func (p *FilePlayer) Prepare(number int) error {
+	if _, ok := p.prepared[number]; ok {
+		return nil
+	}
+
+	resource, ok := p.blorb.Sound(number)
+	if !ok {
+		return fmt.Errorf("No sound %d in Blorb file", number)
+	}
+
+	switch resource.Format {
+	case FormatAIFF:
+		sound, err := decodeAIFF(resource.Data)
+		if err != nil {
+			return err
+		}
+		p.decoded[number] = sound
+	default:
+		return fmt.Errorf("Sound %d has unsupported format %q", number, resource.Format)
+	}
+
+	p.prepared[number] = resource
+	return nil
+}
+
+func (p *FilePlayer) Start(number int, volume int, repeats int, done func()) error {
+	err := p.Prepare(number)
+	if err != nil {
+		return err
+	}
+
+	if repeats == RepeatDefault {
+		repeats = p.blorb.Repeats(number)
+	}
+	if repeats <= 0 {
+		// A file can't play forever, so sounds that would are written once
+		repeats = 1
+	}
+
+	resource := p.prepared[number]
+	var name string
+	var data []byte
+	switch resource.Format {
+	case FormatAIFF:
+		name = fmt.Sprintf("sound-%d.wav", number)
+		data = encodeWAV(p.decoded[number], float64(min(max(volume, 0), MaxVolume))/MaxVolume, repeats)
+	default:
+		return errors.New("Sound has not been prepared")
+	}
+
+	err = os.WriteFile(filepath.Join(p.dir, name), data, 0644)
+	if err != nil {
+		return err
+	}
+
+	done()
+	return nil
+}
+
+// Stop does nothing, since sounds have always finished by the time Start returns
+func (p *FilePlayer) Stop(number int) {}
+
+// Finish releases a prepared sound
+func (p *FilePlayer) Finish(number int) {
+	delete(p.prepared, number)
+	delete(p.decoded, number)
+}
@@ -0,0 +1,150 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func chunk(id string, data []byte) []byte {
+	out := binary.BigEndian.AppendUint32([]byte(id), uint32(len(data)))
+	out = append(out, data...)
+	if len(data)%2 != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// testAIFF builds a mono 16-bit AIFF at 8000Hz
+func testAIFF(samples ...int16) []byte {
+	common := binary.BigEndian.AppendUint16(nil, 1)
+	common = binary.BigEndian.AppendUint32(common, uint32(len(samples)))
+	common = binary.BigEndian.AppendUint16(common, 16)
+	// 8000 as an 80-bit extended float
+	common = binary.BigEndian.AppendUint16(common, 16383+12)
+	common = binary.BigEndian.AppendUint64(common, 8000<<(63-12))
+
+	sound := make([]byte, 8)
+	for _, sample := range samples {
+		sound = binary.BigEndian.AppendUint16(sound, uint16(sample))
+	}
+
+	form := append([]byte("AIFF"), chunk("COMM", common)...)
+	form = append(form, chunk("SSND", sound)...)
+	return chunk("FORM", form)
+}
+
+// testBlorb builds a Blorb file holding an AIFF as sound 3 and Ogg data as sound 4
+func testBlorb() []byte {
+	aiff := testAIFF(0x0102, -2)
+	ogg := chunk(FormatOgg, []byte("OggS"))
+
+	indexLength := 8 + 4 + 2*12
+	aiffOffset := 12 + indexLength
+	oggOffset := aiffOffset + len(aiff)
+
+	index := binary.BigEndian.AppendUint32(nil, 2)
+	for _, entry := range []struct{ number, offset int }{{3, aiffOffset}, {4, oggOffset}} {
+		index = append(index, "Snd "...)
+		index = binary.BigEndian.AppendUint32(index, uint32(entry.number))
+		index = binary.BigEndian.AppendUint32(index, uint32(entry.offset))
+	}
+
+	loop := binary.BigEndian.AppendUint32(nil, 3)
+	loop = binary.BigEndian.AppendUint32(loop, 2)
+
+	form := append([]byte("IFRS"), chunk("RIdx", index)...)
+	form = append(form, aiff...)
+	form = append(form, ogg...)
+	form = append(form, chunk("Loop", loop)...)
+	return chunk("FORM", form)
+}
+
+func TestReadBlorb(t *testing.T) {
+	blorb, err := ReadBlorb(bytes.NewReader(testBlorb()))
+	testassert.NoError(t, err)
+
+	aiff, ok := blorb.Sound(3)
+	testassert.True(t, ok)
+	testassert.Same(t, FormatAIFF, aiff.Format)
+
+	ogg, ok := blorb.Sound(4)
+	testassert.True(t, ok)
+	testassert.Same(t, FormatOgg, ogg.Format)
+
+	_, ok = blorb.Sound(5)
+	testassert.False(t, ok)
+
+	testassert.Same(t, 2, blorb.Repeats(3))
+	testassert.Same(t, 1, blorb.Repeats(4))
+}
+
+func TestFilePlayer_WritesWAV(t *testing.T) {
+	blorb, err := ReadBlorb(bytes.NewReader(testBlorb()))
+	testassert.NoError(t, err)
+
+	dir := t.TempDir()
+	player := NewFilePlayer(blorb, dir)
+
+	finished := false
+	testassert.NoError(t, player.Start(3, MaxVolume, RepeatDefault, func() { finished = true }))
+	testassert.True(t, finished)
+
+	wav, err := os.ReadFile(filepath.Join(dir, "sound-3.wav"))
+	testassert.NoError(t, err)
+	testassert.Same(t, "RIFF", string(wav[:4]))
+	testassert.Same(t, "WAVE", string(wav[8:12]))
+	testassert.Same(t, 8000, int(binary.LittleEndian.Uint32(wav[24:28])))
+
+	// Two samples, played twice as the Loop chunk asks, now little-endian
+	data := wav[44:]
+	testassert.True(t, bytes.Equal([]byte{0x02, 0x01, 0xfe, 0xff, 0x02, 0x01, 0xfe, 0xff}, data))
+}
+
+func TestFilePlayer_ScalesVolume(t *testing.T) {
+	blorb, err := ReadBlorb(bytes.NewReader(testBlorb()))
+	testassert.NoError(t, err)
+
+	dir := t.TempDir()
+	player := NewFilePlayer(blorb, dir)
+	testassert.NoError(t, player.Start(3, MaxVolume/2, 1, func() {}))
+
+	wav, err := os.ReadFile(filepath.Join(dir, "sound-3.wav"))
+	testassert.NoError(t, err)
+	testassert.Same(t, int16(0x0081), int16(binary.LittleEndian.Uint16(wav[44:])))
+	testassert.Same(t, int16(-1), int16(binary.LittleEndian.Uint16(wav[46:])))
+}
+
+func TestFilePlayer_OggUnsupported(t *testing.T) {
+	blorb, err := ReadBlorb(bytes.NewReader(testBlorb()))
+	testassert.NoError(t, err)
+
+	player := NewFilePlayer(blorb, t.TempDir())
+	testassert.ErrorMessage(t, `Sound 4 has unsupported format "OGGV"`, player.Prepare(4))
+}
+
+func TestFilePlayer_MissingSound(t *testing.T) {
+	blorb, err := ReadBlorb(bytes.NewReader(testBlorb()))
+	testassert.NoError(t, err)
+
+	player := NewFilePlayer(blorb, t.TempDir())
+	testassert.ErrorMessage(t, "No sound 9 in Blorb file", player.Prepare(9))
+}
+
+func TestLogPlayer(t *testing.T) {
+	log := bytes.Buffer{}
+	rang := false
+	player := LogPlayer{Log: &log, Bell: func() { rang = true }}
+
+	player.Beep(true)
+	finished := false
+	testassert.NoError(t, player.Start(3, 8, RepeatForever, func() { finished = true }))
+
+	testassert.True(t, rang)
+	testassert.True(t, finished)
+	testassert.Same(t, "beep high=true\nstart 3 volume=8 repeats=-1\n", log.String())
+}
@@ -0,0 +1,114 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// aiff is the decoded sample data of an AIFF sound
+type aiff struct {
+	channels   int
+	sampleBits int
+	sampleRate int
+	samples    []byte // Big-endian signed samples, as stored in the file
+}
+
+func decodeAIFF(data []byte) (aiff, error) {
+	if len(data) < 12 || string(data[:4]) != "FORM" || string(data[8:12]) != "AIFF" {
+		return aiff{}, errors.New("Not an AIFF sound")
+	}
+
+	sound := aiff{}
+	foundCommon, foundSound := false, false
+
+	for offset := 12; offset+8 <= len(data); {
+		id, chunk, next, err := readChunk(data, offset)
+		if err != nil {
+			return aiff{}, err
+		}
+
+		switch id {
+		case "COMM":
+			if len(chunk) < 18 {
+				return aiff{}, errors.New("AIFF COMM chunk too short")
+			}
+			sound.channels = int(binary.BigEndian.Uint16(chunk[0:2]))
+			sound.sampleBits = int(binary.BigEndian.Uint16(chunk[6:8]))
+			sound.sampleRate = int(extendedToFloat(chunk[8:18]))
+			foundCommon = true
+		case "SSND":
+			if len(chunk) < 8 {
+				return aiff{}, errors.New("AIFF SSND chunk too short")
+			}
+			start := 8 + int(binary.BigEndian.Uint32(chunk[0:4]))
+			sound.samples = chunk[min(start, len(chunk)):]
+			foundSound = true
+		}
+		offset = next
+	}
+
+	switch {
+	case !foundCommon || !foundSound:
+		return aiff{}, errors.New("AIFF sound is missing its COMM or SSND chunk")
+	case sound.sampleBits != 8 && sound.sampleBits != 16:
+		return aiff{}, errors.New("Only 8 and 16 bit AIFF sounds are supported")
+	}
+
+	return sound, nil
+}
+
+// extendedToFloat converts the 80-bit IEEE extended float AIFF uses for its sample rate
+func extendedToFloat(data []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(data[0:2]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(data[2:10])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if data[0]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// encodeWAV converts the AIFF samples to a little-endian PCM WAV file. Volume scales the samples,
+// from 0 for silent to 1 for unchanged, and the samples are repeated the given number of times.
+func encodeWAV(sound aiff, volume float64, repeats int) []byte {
+	samples := make([]byte, 0, len(sound.samples)*repeats)
+	for range repeats {
+		samples = append(samples, sound.samples...)
+	}
+
+	if sound.sampleBits == 16 {
+		for i := 0; i+1 < len(samples); i += 2 {
+			sample := float64(int16(binary.BigEndian.Uint16(samples[i:])))
+			binary.LittleEndian.PutUint16(samples[i:], uint16(int16(sample*volume)))
+		}
+	} else {
+		// 8-bit WAV samples are unsigned, unlike AIFF
+		for i, b := range samples {
+			samples[i] = byte(int(float64(int8(b))*volume) + 128)
+		}
+	}
+
+	bytesPerFrame := sound.channels * sound.sampleBits / 8
+	out := bytes.Buffer{}
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(36+len(samples)))
+	out.WriteString("WAVEfmt ")
+	binary.Write(&out, binary.LittleEndian, uint32(16))
+	binary.Write(&out, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&out, binary.LittleEndian, uint16(sound.channels))
+	binary.Write(&out, binary.LittleEndian, uint32(sound.sampleRate))
+	binary.Write(&out, binary.LittleEndian, uint32(sound.sampleRate*bytesPerFrame))
+	binary.Write(&out, binary.LittleEndian, uint16(bytesPerFrame))
+	binary.Write(&out, binary.LittleEndian, uint16(sound.sampleBits))
+	out.WriteString("data")
+	binary.Write(&out, binary.LittleEndian, uint32(len(samples)))
+	out.Write(samples)
+
+	return out.Bytes()
+}
@@ -0,0 +1,346 @@
+package teststory
+
+import (
+	"fmt"
+)
+
+type operandType byte
+
+const (
+	ot_Large    operandType = 0
+	ot_Small    operandType = 1
+	ot_Variable operandType = 2
+	ot_Omitted  operandType = 3
+)
+
+type operandKind uint8
+
+const (
+	ok_Value   operandKind = 0
+	ok_Routine operandKind = 1 // Packed address of a named routine
+	ok_String  operandKind = 2 // Packed address of a named string
+	ok_Array   operandKind = 3 // Byte address of a named array
+	ok_Word    operandKind = 4 // Byte address of a dictionary entry
+	ok_Offset  operandKind = 5 // Jump offset to a label
+)
+
+// Operand is a value, variable or reference in an instruction. References are resolved once
+// the story is laid out, and are always large constants.
+type Operand struct {
+	optype operandType
+	kind   operandKind
+	value  word
+	name   string
+}
+
+func Large(value word) Operand {
+	return Operand{optype: ot_Large, value: value}
+}
+
+func Small(value byte) Operand {
+	return Operand{optype: ot_Small, value: word(value)}
+}
+
+// Var reads a variable: 0 is the stack, 0x01-0x0f are locals and 0x10-0xff are globals
+func Var(variable byte) Operand {
+	return Operand{optype: ot_Variable, value: word(variable)}
+}
+
+// RoutineAddress is the packed address of a routine in Story.Routines
+func RoutineAddress(name string) Operand {
+	return Operand{optype: ot_Large, kind: ok_Routine, name: name}
+}
+
+// StringAddress is the packed address of a string in Story.Strings
+func StringAddress(name string) Operand {
+	return Operand{optype: ot_Large, kind: ok_String, name: name}
+}
+
+// ArrayAddress is the address of an array in Story.Arrays
+func ArrayAddress(name string) Operand {
+	return Operand{optype: ot_Large, kind: ok_Array, name: name}
+}
+
+// WordAddress is the address of a word's entry in Story.Dictionary
+func WordAddress(text string) Operand {
+	return Operand{optype: ot_Large, kind: ok_Word, name: text}
+}
+
+// Offset is the offset jump needs to reach a label
+func Offset(label string) Operand {
+	return Operand{optype: ot_Large, kind: ok_Offset, name: label}
+}
+
+type instructionForm uint8
+
+const (
+	if_Label    instructionForm = 0 // Marks a place in the code without taking any space
+	if_0OP      instructionForm = 1
+	if_1OP      instructionForm = 2
+	if_2OP      instructionForm = 3
+	if_VAR      instructionForm = 4
+	if_Extended instructionForm = 5
+)
+
+type branch struct {
+	label  string
+	onTrue bool
+	result int // -1 to branch to the label, otherwise the value to return
+}
+
+// Instruction is one instruction of a routine. Build one with the constructor for its operand
+// count, then add a store, branch or text as its opcode requires.
+type Instruction struct {
+	form     instructionForm
+	number   byte
+	operands []Operand
+	store    *byte
+	branch   *branch
+	text     *string
+	label    string
+}
+
+// Op0 is a 0OP instruction, numbered from 0x00 (rtrue) as in the standard's opcode tables
+func Op0(number byte) Instruction {
+	return Instruction{form: if_0OP, number: number}
+}
+
+// Op1 is a 1OP instruction, numbered from 0x00 (jz)
+func Op1(number byte, operand Operand) Instruction {
+	return Instruction{form: if_1OP, number: number, operands: []Operand{operand}}
+}
+
+// Op2 is a 2OP instruction, numbered from 0x01 (je). It uses the long form when it can, and
+// the variable form for large operands or any other number of operands.
+func Op2(number byte, operands ...Operand) Instruction {
+	return Instruction{form: if_2OP, number: number, operands: operands}
+}
+
+// OpVar is a VAR instruction, numbered from 0x00 (call)
+func OpVar(number byte, operands ...Operand) Instruction {
+	return Instruction{form: if_VAR, number: number, operands: operands}
+}
+
+// OpExt is an extended instruction, numbered from 0x00 (save)
+func OpExt(number byte, operands ...Operand) Instruction {
+	return Instruction{form: if_Extended, number: number, operands: operands}
+}
+
+// Label marks the place a branch or jump can go to. Labels belong to the routine they're in.
+func Label(name string) Instruction {
+	return Instruction{form: if_Label, label: name}
+}
+
+// Store sets the variable an instruction stores its result in
+func (instruction Instruction) Store(variable byte) Instruction {
+	instruction.store = &variable
+	return instruction
+}
+
+// Branch goes to a label when the instruction's condition matches onTrue
+func (instruction Instruction) Branch(label string, onTrue bool) Instruction {
+	instruction.branch = &branch{label: label, onTrue: onTrue, result: -1}
+	return instruction
+}
+
+// BranchReturn returns from the routine instead of branching, with true or false
+func (instruction Instruction) BranchReturn(result bool, onTrue bool) Instruction {
+	instruction.branch = &branch{onTrue: onTrue}
+	if result {
+		instruction.branch.result = 1
+	}
+	return instruction
+}
+
+// Text adds the string printed by print and print_ret
+func (instruction Instruction) Text(text string) Instruction {
+	instruction.text = &text
+	return instruction
+}
+
+// isLong reports whether a 2OP instruction fits the long form, which only has room for two
+// small constants or variables
+func (instruction Instruction) isLong() bool {
+	if instruction.form != if_2OP || len(instruction.operands) != 2 {
+		return false
+	}
+	for _, operand := range instruction.operands {
+		if operand.optype == ot_Large {
+			return false
+		}
+	}
+	return true
+}
+
+// typeBytes is the number of operand type bytes. call_vs2 and call_vn2 have room for eight.
+func (instruction Instruction) typeBytes() int {
+	switch {
+	case instruction.form == if_Extended:
+		return 1
+	case instruction.form == if_VAR && (instruction.number == 0x0c || instruction.number == 0x1a):
+		return 2
+	case instruction.form == if_VAR || (instruction.form == if_2OP && !instruction.isLong()):
+		return 1
+	}
+	return 0
+}
+
+// opcode encodes everything up to the operands
+func (instruction Instruction) opcode() ([]byte, error) {
+	operands := instruction.operands
+	switch instruction.form {
+	case if_0OP:
+		if len(operands) != 0 || instruction.number > 0x0f {
+			return nil, fmt.Errorf("0OP %02x takes no operands and must be below 0x10", instruction.number)
+		}
+		return []byte{0xb0 | instruction.number}, nil
+	case if_1OP:
+		if len(operands) != 1 || instruction.number > 0x0f {
+			return nil, fmt.Errorf("1OP %02x takes one operand and must be below 0x10", instruction.number)
+		}
+		return []byte{0x80 | byte(operands[0].optype)<<4 | instruction.number}, nil
+	case if_2OP:
+		if instruction.number > 0x1f {
+			return nil, fmt.Errorf("2OP %02x must be below 0x20", instruction.number)
+		}
+		if instruction.isLong() {
+			opcode := instruction.number
+			if operands[0].optype == ot_Variable {
+				opcode |= 0x40
+			}
+			if operands[1].optype == ot_Variable {
+				opcode |= 0x20
+			}
+			return []byte{opcode}, nil
+		}
+		return instruction.withTypes(0xc0 | instruction.number)
+	case if_VAR:
+		if instruction.number > 0x1f {
+			return nil, fmt.Errorf("VAR %02x must be below 0x20", instruction.number)
+		}
+		return instruction.withTypes(0xe0 | instruction.number)
+	case if_Extended:
+		return instruction.withTypes(0xbe, instruction.number)
+	}
+	return nil, nil
+}
+
+func (instruction Instruction) withTypes(opcode ...byte) ([]byte, error) {
+	slots := instruction.typeBytes() * 4
+	if len(instruction.operands) > slots {
+		return nil, fmt.Errorf("Opcode %02x has %d operands, only %d fit", opcode[len(opcode)-1], len(instruction.operands), slots)
+	}
+
+	types := make([]byte, instruction.typeBytes())
+	for i := range slots {
+		optype := ot_Omitted
+		if i < len(instruction.operands) {
+			optype = instruction.operands[i].optype
+		}
+		types[i/4] |= byte(optype) << (6 - 2*(i%4))
+	}
+	return append(opcode, types...), nil
+}
+
+// size is the number of bytes the instruction assembles to, which doesn't depend on where it
+// or anything it refers to ends up
+func (instruction Instruction) size(a *assembler) (int, error) {
+	if instruction.form == if_Label {
+		return 0, nil
+	}
+
+	opcode, err := instruction.opcode()
+	if err != nil {
+		return 0, err
+	}
+
+	size := len(opcode)
+	for _, operand := range instruction.operands {
+		size++
+		if operand.optype == ot_Large {
+			size++
+		}
+	}
+	if instruction.store != nil {
+		size++
+	}
+	if instruction.branch != nil {
+		size++
+		if instruction.branch.result < 0 {
+			size++
+		}
+	}
+	if instruction.text != nil {
+		text, err := a.encodeText(*instruction.text)
+		if err != nil {
+			return 0, err
+		}
+		size += len(text)
+	}
+	return size, nil
+}
+
+// encode assembles the instruction at an address, with the labels of the routine it's in
+func (instruction Instruction) encode(a *assembler, address int, labels map[string]int) ([]byte, error) {
+	if instruction.form == if_Label {
+		return nil, nil
+	}
+
+	size, err := instruction.size(a)
+	if err != nil {
+		return nil, err
+	}
+	data, err := instruction.opcode()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, operand := range instruction.operands {
+		value, err := a.resolve(operand, address+size, labels)
+		if err != nil {
+			return nil, err
+		}
+
+		if operand.optype == ot_Large {
+			data = append(data, byte(value>>8))
+		}
+		data = append(data, byte(value))
+	}
+
+	if instruction.store != nil {
+		data = append(data, *instruction.store)
+	}
+
+	if instruction.branch != nil {
+		condition := byte(0)
+		if instruction.branch.onTrue {
+			condition = 0x80
+		}
+
+		if instruction.branch.result >= 0 {
+			data = append(data, condition|0x40|byte(instruction.branch.result))
+		} else {
+			target, ok := labels[instruction.branch.label]
+			if !ok {
+				return nil, fmt.Errorf("Branch to unknown label %q", instruction.branch.label)
+			}
+
+			// Offsets count from the end of the branch data, less 2
+			offset := target - (address + len(data) + 2) + 2
+			if offset < -0x2000 || offset >= 0x2000 || offset == 0 || offset == 1 {
+				return nil, fmt.Errorf("Label %q is out of reach of a branch", instruction.branch.label)
+			}
+			data = append(data, condition|byte(offset>>8)&0x3f, byte(offset))
+		}
+	}
+
+	if instruction.text != nil {
+		text, err := a.encodeText(*instruction.text)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, text...)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,480 @@
+// Package teststory assembles small stories from Go code, so the interpreter can be tested
+// without a compiler or a hand-built story file.
+package teststory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+)
+
+type word = uint16
+
+// Story describes the parts of a story. Anything left out gets an empty default, so a story
+// can be as small as the code it runs.
+type Story struct {
+	Version    int // Defaults to 3
+	Release    word
+	Serial     string            // Six characters, defaults to "000000"
+	Globals    map[byte]word     // Initial values by variable number, from 0x10
+	Arrays     map[string][]byte // Named arrays in dynamic memory
+	Objects    []Object          // Starting with object 1
+	Defaults   map[byte]word     // Property defaults by property number, the rest are 0
+	Dictionary Dictionary
+	Main       []Instruction      // Code run from the initial program counter
+	Routines   map[string]Routine // Called with RoutineAddress
+	Strings    map[string]string  // Printed with StringAddress
+
+	BadChecksum bool // Store a checksum that doesn't match the story, so verify fails
+}
+
+type Object struct {
+	Name       string
+	Attributes []int
+	Parent     word
+	Sibling    word
+	Child      word
+	Properties []Property
+}
+
+type Property struct {
+	Number byte
+	Data   []byte
+}
+
+type Dictionary struct {
+	Separators string
+	Words      []string
+	DataBytes  int  // Bytes after each encoded word, which Inform uses for flags
+	Unsorted   bool // Keep the words in the order given, and mark the dictionary as unsorted
+}
+
+type Routine struct {
+	Locals []word // Initial values of the locals, which V5+ stories don't have
+	Code   []Instruction
+}
+
+// assembler lays out a story and fills in the addresses instructions refer to
+type assembler struct {
+	story    Story
+	version  int
+	charset  zstring.Charset
+	unicode  zstring.UnicodeTable
+	data     []byte
+	arrays   map[string]int
+	words    map[string]int
+	routines map[string]int
+	strings  map[string]int
+}
+
+// Memory assembles the story and loads it
+func (story Story) Memory() (*memory.Memory, error) {
+	data, err := story.Assemble()
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemoryFromBytes(data, func(m *memory.Memory) {})
+}
+
+// Assemble lays out the story as a story file would be: the header, abbreviations, globals,
+// arrays and objects in dynamic memory, then the dictionary, then code and strings in high
+// memory
+func (story Story) Assemble() ([]byte, error) {
+	if story.Version == 0 {
+		story.Version = 3
+	}
+	if story.Version < 1 || story.Version > 8 {
+		return nil, fmt.Errorf("Version %d doesn't exist", story.Version)
+	}
+	if story.Version == 6 {
+		return nil, errors.New("Version 6 stories start in a packed routine, which isn't supported")
+	}
+	if story.Serial == "" {
+		story.Serial = "000000"
+	}
+	if len(story.Serial) != 6 {
+		return nil, errors.New("Serial must be six characters")
+	}
+
+	charset, err := zstring.NewStaticCharset(zstring.GetDefaultAlphabet(story.Version), zstring.GetDefaultCtrlCharMapping(story.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	a := &assembler{
+		story:    story,
+		version:  story.Version,
+		charset:  charset,
+		unicode:  zstring.GetDefaultUnicodeTable(story.Version),
+		data:     make([]byte, 0x40),
+		arrays:   map[string]int{},
+		words:    map[string]int{},
+		routines: map[string]int{},
+		strings:  map[string]int{},
+	}
+	return a.assemble()
+}
+
+func (a *assembler) assemble() ([]byte, error) {
+	a.data[0x00] = byte(a.version)
+	a.setWord(0x02, a.story.Release)
+	copy(a.data[0x12:0x18], a.story.Serial)
+
+	// Every abbreviation is an empty string, placed once its address is known
+	abbreviations := len(a.data)
+	a.setWord(0x18, word(abbreviations))
+	a.data = append(a.data, make([]byte, 96*2)...)
+
+	globals := len(a.data)
+	a.setWord(0x0c, word(globals))
+	a.data = append(a.data, make([]byte, 240*2)...)
+	for variable, value := range a.story.Globals {
+		if variable < 0x10 {
+			return nil, fmt.Errorf("Variable %02x is not a global", variable)
+		}
+		a.setWord(globals+2*int(variable-0x10), value)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(a.story.Arrays)) {
+		a.arrays[name] = len(a.data)
+		a.data = append(a.data, a.story.Arrays[name]...)
+	}
+
+	err := a.assembleObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	a.setWord(0x0e, word(len(a.data)))
+
+	empty, err := a.encodeText("")
+	if err != nil {
+		return nil, err
+	}
+	a.align(2)
+	for i := range 96 {
+		a.setWord(abbreviations+2*i, word(len(a.data)/2))
+	}
+	a.data = append(a.data, empty...)
+
+	err = a.assembleDictionary()
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.assembleCode()
+	if err != nil {
+		return nil, err
+	}
+
+	// The file length is stored divided by a scale that depends on the version
+	scale := 2
+	switch {
+	case a.version >= 6:
+		scale = 8
+	case a.version >= 4:
+		scale = 4
+	}
+	a.align(scale)
+	a.setWord(0x1a, word(len(a.data)/scale))
+
+	checksum := word(0)
+	for _, b := range a.data[0x40:] {
+		checksum += word(b)
+	}
+	if a.story.BadChecksum {
+		checksum++
+	}
+	a.setWord(0x1c, checksum)
+
+	return a.data, nil
+}
+
+func (a *assembler) setWord(address int, value word) {
+	a.data[address] = byte(value >> 8)
+	a.data[address+1] = byte(value)
+}
+
+// align pads the story to a multiple of n bytes
+func (a *assembler) align(n int) {
+	for len(a.data)%n != 0 {
+		a.data = append(a.data, 0)
+	}
+}
+
+func (a *assembler) encodeText(text string) (zstring.ZString, error) {
+	return zstring.EncodeText([]rune(text), a.charset, a.unicode, a.version)
+}
+
+func (a *assembler) assembleObjects() error {
+	defaults, entrySize, attributeBytes, maxLength := 31, 9, 4, 8
+	if a.version > 3 {
+		defaults, entrySize, attributeBytes, maxLength = 63, 14, 6, 64
+	}
+
+	table := len(a.data)
+	a.setWord(0x0a, word(table))
+	a.data = append(a.data, make([]byte, defaults*2)...)
+	for property, value := range a.story.Defaults {
+		if property == 0 || int(property) > defaults {
+			return fmt.Errorf("Property %d doesn't exist, so it has no default", property)
+		}
+		a.setWord(table+2*int(property-1), value)
+	}
+
+	entries := len(a.data)
+	a.data = append(a.data, make([]byte, entrySize*len(a.story.Objects))...)
+
+	for i, object := range a.story.Objects {
+		entry := a.data[entries+entrySize*i : entries+entrySize*(i+1)]
+		for _, attribute := range object.Attributes {
+			if attribute < 0 || attribute >= attributeBytes*8 {
+				return fmt.Errorf("Object %d has attribute %d, which doesn't exist", i+1, attribute)
+			}
+			entry[attribute/8] |= 0x80 >> (attribute % 8)
+		}
+
+		properties := len(a.data)
+		if a.version <= 3 {
+			entry[4], entry[5], entry[6] = byte(object.Parent), byte(object.Sibling), byte(object.Child)
+			entry[7], entry[8] = byte(properties>>8), byte(properties)
+		} else {
+			links := []word{object.Parent, object.Sibling, object.Child, word(properties)}
+			for j, link := range links {
+				entry[6+2*j], entry[7+2*j] = byte(link>>8), byte(link)
+			}
+		}
+
+		name := zstring.ZString{}
+		if object.Name != "" {
+			var err error
+			name, err = a.encodeText(object.Name)
+			if err != nil {
+				return err
+			}
+		}
+		a.data = append(a.data, byte(len(name)/2))
+		a.data = append(a.data, name...)
+
+		// Properties are listed in descending order
+		sorted := slices.SortedFunc(slices.Values(object.Properties), func(x Property, y Property) int {
+			return int(y.Number) - int(x.Number)
+		})
+		for j, property := range sorted {
+			length := len(property.Data)
+			if property.Number == 0 || int(property.Number) > defaults || length == 0 || length > maxLength ||
+				(j > 0 && property.Number == sorted[j-1].Number) {
+				return fmt.Errorf("Object %d has an invalid property %d of %d bytes", i+1, property.Number, length)
+			}
+
+			switch {
+			case a.version <= 3:
+				a.data = append(a.data, byte(length-1)<<5|property.Number)
+			case length == 1:
+				a.data = append(a.data, property.Number)
+			case length == 2:
+				a.data = append(a.data, 0x40|property.Number)
+			default:
+				a.data = append(a.data, 0x80|property.Number, 0x80|byte(length%64))
+			}
+			a.data = append(a.data, property.Data...)
+		}
+		a.data = append(a.data, 0)
+	}
+
+	return nil
+}
+
+func (a *assembler) assembleDictionary() error {
+	dictionary := a.story.Dictionary
+	a.setWord(0x08, word(len(a.data)))
+
+	a.data = append(a.data, byte(len(dictionary.Separators)))
+	a.data = append(a.data, dictionary.Separators...)
+
+	type entry struct {
+		text    string
+		encoded zstring.ZString
+	}
+	entries := make([]entry, 0, len(dictionary.Words))
+	for _, text := range dictionary.Words {
+		encoded, err := zstring.EncodeWord([]rune(text), a.charset, a.unicode, a.version)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{text, encoded})
+	}
+	count := word(len(entries))
+	if dictionary.Unsorted {
+		count = -count // A negative count marks the entries as unsorted
+	} else {
+		slices.SortFunc(entries, func(x entry, y entry) int { return bytes.Compare(x.encoded, y.encoded) })
+	}
+
+	entryLength := zstring.GetDictionaryWordLength(a.version)*2/3 + dictionary.DataBytes
+	a.data = append(a.data, byte(entryLength), byte(count>>8), byte(count))
+	for _, entry := range entries {
+		a.words[entry.text] = len(a.data)
+		a.data = append(a.data, entry.encoded...)
+		a.data = append(a.data, make([]byte, dictionary.DataBytes)...)
+	}
+
+	return nil
+}
+
+// packing is the number of bytes a packed address counts in. Version 7's routine and string
+// offsets are left at 0.
+func (a *assembler) packing() int {
+	switch {
+	case a.version >= 8:
+		return 8
+	case a.version >= 4:
+		return 4
+	}
+	return 2
+}
+
+// assembleCode lays out the main code, routines and strings before encoding any instructions,
+// since instructions can refer to anything that comes after them
+func (a *assembler) assembleCode() error {
+	a.align(a.packing())
+	a.setWord(0x04, word(len(a.data)))
+
+	type block struct {
+		address int
+		code    []Instruction
+		labels  map[string]int
+	}
+
+	main := block{address: len(a.data), code: a.story.Main}
+	a.setWord(0x06, word(main.address))
+	end, err := a.layout(&main.labels, main.address, main.code)
+	if err != nil {
+		return err
+	}
+
+	blocks := []block{main}
+	for _, name := range slices.Sorted(maps.Keys(a.story.Routines)) {
+		routine := a.story.Routines[name]
+		if len(routine.Locals) > 15 {
+			return fmt.Errorf("Routine %s has more than 15 locals", name)
+		}
+
+		header := 1
+		if a.version < 5 {
+			header += 2 * len(routine.Locals)
+		}
+
+		address := alignTo(end, a.packing())
+		a.routines[name] = address
+		b := block{address: address + header, code: routine.Code}
+		end, err = a.layout(&b.labels, b.address, b.code)
+		if err != nil {
+			return fmt.Errorf("Routine %s: %w", name, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	strings := map[string]zstring.ZString{}
+	for _, name := range slices.Sorted(maps.Keys(a.story.Strings)) {
+		encoded, err := a.encodeText(a.story.Strings[name])
+		if err != nil {
+			return err
+		}
+		strings[name] = encoded
+		a.strings[name] = alignTo(end, a.packing())
+		end = a.strings[name] + len(encoded)
+	}
+
+	a.data = append(a.data, make([]byte, end-len(a.data))...)
+
+	for name, address := range a.routines {
+		routine := a.story.Routines[name]
+		a.data[address] = byte(len(routine.Locals))
+		if a.version < 5 {
+			for i, local := range routine.Locals {
+				a.setWord(address+1+2*i, local)
+			}
+		}
+	}
+	for name, address := range a.strings {
+		copy(a.data[address:], strings[name])
+	}
+
+	for _, b := range blocks {
+		address := b.address
+		for _, instruction := range b.code {
+			data, err := instruction.encode(a, address, b.labels)
+			if err != nil {
+				return err
+			}
+			copy(a.data[address:], data)
+			address += len(data)
+		}
+	}
+
+	return nil
+}
+
+// layout finds the labels in some code starting at an address, and returns where the code ends
+func (a *assembler) layout(labels *map[string]int, address int, code []Instruction) (int, error) {
+	*labels = map[string]int{}
+	for _, instruction := range code {
+		if instruction.form == if_Label {
+			if _, ok := (*labels)[instruction.label]; ok {
+				return 0, fmt.Errorf("Label %q is used twice", instruction.label)
+			}
+			(*labels)[instruction.label] = address
+		}
+
+		size, err := instruction.size(a)
+		if err != nil {
+			return 0, err
+		}
+		address += size
+	}
+	return address, nil
+}
+
+func alignTo(address int, n int) int {
+	return (address + n - 1) / n * n
+}
+
+// resolve finds the value of an operand in an instruction ending at next
+func (a *assembler) resolve(operand Operand, next int, labels map[string]int) (word, error) {
+	var address int
+	var ok bool
+
+	switch operand.kind {
+	case ok_Value:
+		return operand.value, nil
+	case ok_Routine:
+		address, ok = a.routines[operand.name]
+		return word(address / a.packing()), a.found(ok, "routine", operand.name)
+	case ok_String:
+		address, ok = a.strings[operand.name]
+		return word(address / a.packing()), a.found(ok, "string", operand.name)
+	case ok_Array:
+		address, ok = a.arrays[operand.name]
+		return word(address), a.found(ok, "array", operand.name)
+	case ok_Word:
+		address, ok = a.words[operand.name]
+		return word(address), a.found(ok, "dictionary word", operand.name)
+	case ok_Offset:
+		address, ok = labels[operand.name]
+		return word(address - next + 2), a.found(ok, "label", operand.name)
+	}
+	return 0, nil
+}
+
+func (a *assembler) found(ok bool, kind string, name string) error {
+	if !ok {
+		return fmt.Errorf("Unknown %s %q", kind, name)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package zmachine
+
+import (
+	"slices"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+// instructionCache keeps the instructions decoded from high memory, which a story can never
+// write to, so each one is only decoded the first time it runs
+type instructionCache struct {
+	base         memory.Address
+	instructions []*Instruction // Indexed by address from the base
+}
+
+func newInstructionCache(m *memory.Memory) *instructionCache {
+	base := m.GetHighMemoryAddress()
+	return &instructionCache{base: base, instructions: make([]*Instruction, max(m.Size()-int(base), 0))}
+}
+
+// decodeInstruction reads the instruction at an address, from the cache when it can
+func (zmachine *ZMachine) decodeInstruction(address memory.Address) (Instruction, memory.Address, error) {
+	cache := zmachine.decoded
+	if cache == nil || address < cache.base || int(address-cache.base) >= len(cache.instructions) {
+		return zmachine.readInstruction(address)
+	}
+
+	cached := cache.instructions[address-cache.base]
+	if cached == nil {
+		instruction, _, err := zmachine.readInstruction(address)
+		if err != nil {
+			return instruction, 0, err
+		}
+		cached = &instruction
+		cache.instructions[address-cache.base] = cached
+	}
+
+	instruction := *cached
+	instruction.StoreVariable.zmachine = zmachine
+	if slices.Contains(instruction.OperandTypes, OT_Variable) {
+		// Variable operands are replaced by their values before the handler runs
+		instruction.Operands = slices.Clone(instruction.Operands)
+	}
+	return instruction, instruction.NextAddress, nil
+}
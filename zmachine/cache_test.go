@@ -0,0 +1,117 @@
+package zmachine
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+// loopAddress is in high memory of blank.z3
+const loopAddress memory.Address = 0x5000
+
+// writeLoop writes a loop of arithmetic and branches that never ends, for benchmarking
+func writeLoop(zmachine *ZMachine) {
+	zmachine.Memory.SetBytes(loopAddress, []byte{
+		0x54, 0x10, 0x03, 0x10, // add g0 #03 -> g0
+		0x56, 0x10, 0x05, 0x11, // mul g0 #05 -> g1
+		0x41, 0x11, 0x00, 0x42, // je g1 #00 ?~500c
+		0x8c, 0xff, 0xf3, // jump 5000
+	})
+	zmachine.Stack.Frame(1).Counter = loopAddress
+}
+
+func TestDecodeInstruction_CachesHighMemory(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	zmachine.decoded = newInstructionCache(zmachine.Memory)
+	writeLoop(zmachine)
+
+	first, next, err := zmachine.decodeInstruction(loopAddress)
+	testassert.NoError(t, err)
+	testassert.Same(t, loopAddress+4, next)
+	testassert.Same(t, "add", first.Name())
+
+	// Variable operands are resolved in place, which mustn't reach the cached instruction
+	first.Operands[0] = 0xff
+	zmachine.Memory.WriteByte(loopAddress+2, 0x07)
+
+	second, _, err := zmachine.decodeInstruction(loopAddress)
+	testassert.NoError(t, err)
+	testassert.Same(t, Operand(0x10), second.Operands[0])
+	testassert.Same(t, Operand(0x03), second.Operands[1])
+}
+
+func TestDecodeInstruction_ReadsDynamicMemory(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	zmachine.decoded = newInstructionCache(zmachine.Memory)
+
+	zmachine.Memory.SetBytes(0x100, []byte{0x14, 0x01, 0x02, 0x00}) // add #01 #02 -> sp
+	zmachine.decodeInstruction(0x100)
+	zmachine.Memory.WriteByte(0x102, 0x05)
+
+	instruction, _, err := zmachine.decodeInstruction(0x100)
+	testassert.NoError(t, err)
+	testassert.Same(t, Operand(0x05), instruction.Operands[1])
+}
+
+func TestLookupOpcode_UsesLatestRevision(t *testing.T) {
+	type spec struct {
+		version int
+		opcode  Opcode
+		name    string
+	}
+
+	tests := map[string]spec{
+		"V3 read":           {version: 3, opcode: 0xe4, name: "read"},
+		"V5 read stores":    {version: 5, opcode: 0xe4, name: "read"},
+		"V4 set_text_style": {version: 4, opcode: 0xf1, name: "set_text_style"},
+		"V5 extended":       {version: 5, opcode: 0xbe0d, name: "set_true_colour"},
+		"V3 no extended":    {version: 3, opcode: 0xbe0d, name: ""},
+		"V3 no read_char":   {version: 3, opcode: 0xf6, name: ""},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			info, ok := lookupOpcode(s.version, s.opcode)
+			testassert.Same(t, s.name != "", ok)
+			if ok {
+				testassert.Same(t, s.name, info.Name())
+			}
+		})
+	}
+
+	info, _ := lookupOpcode(5, 0xe4)
+	testassert.True(t, info.StoresResult())
+}
+
+func benchmarkExecution(b *testing.B, cached bool) {
+	m, err := memory.NewMemoryFromFile("./blank.z3", func(m *memory.Memory) {})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	zmachine := &ZMachine{Memory: m, Stack: NewCallStack()}
+	zmachine.Stack.Push(Frame{}, 0)
+	zmachine.Stack.Push(Frame{}, 0)
+	if cached {
+		zmachine.decoded = newInstructionCache(zmachine.Memory)
+	}
+	writeLoop(zmachine)
+
+	b.ResetTimer()
+	for range b.N {
+		err := zmachine.executeNextInstruction()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "instructions/s")
+}
+
+func BenchmarkExecute_Cached(b *testing.B) {
+	benchmarkExecution(b, true)
+}
+
+func BenchmarkExecute_Uncached(b *testing.B) {
+	benchmarkExecution(b, false)
+}
@@ -0,0 +1,194 @@
+package zmachine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+const (
+	StackWords = 0x8000 // Words shared by the locals and evaluation stacks of every frame
+	MaxFrames  = 1024   // Deepest routine nesting allowed
+)
+
+var (
+	ErrStackOverflow  = errors.New("Stack overflow")
+	ErrStackUnderflow = errors.New("Stack underflow")
+)
+
+// Frame is a single routine call. Its locals and evaluation stack are kept by the CallStack.
+type Frame struct {
+	Routine        memory.Address // Address of the routine's header, or 0 for the main routine
+	Counter        memory.Address
+	ArgCount       int
+	DiscardReturn  bool
+	ReturnVariable Variable
+	base           int // Index of the frame's first local in the stack's words
+	locals         int
+}
+
+// CallStack holds every routine call. As in real interpreters, the locals and evaluation stacks
+// share one array of words: each frame points at its first local, and its evaluation stack runs
+// from its last local up to the next frame's locals. Neither array grows once allocated, so
+// frame pointers stay valid and calls don't allocate.
+type CallStack struct {
+	words  []word
+	frames []Frame
+}
+
+func NewCallStack() CallStack {
+	return CallStack{words: make([]word, 0, StackWords), frames: make([]Frame, 0, MaxFrames)}
+}
+
+// Push adds a frame with the given number of locals, all zero, and returns the locals so they
+// can be filled in
+func (stack *CallStack) Push(frame Frame, locals int) ([]word, error) {
+	if stack.frames == nil {
+		*stack = NewCallStack()
+	}
+
+	if len(stack.frames) == cap(stack.frames) {
+		return nil, fmt.Errorf("%w: more than %d nested routine calls", ErrStackOverflow, MaxFrames)
+	}
+	if len(stack.words)+locals > cap(stack.words) {
+		return nil, fmt.Errorf("%w: no room for the locals of routine %x", ErrStackOverflow, frame.Routine)
+	}
+
+	frame.base, frame.locals = len(stack.words), locals
+	stack.frames = append(stack.frames, frame)
+	stack.words = stack.words[:frame.base+locals]
+	clear(stack.words[frame.base:])
+	return stack.words[frame.base : frame.base+locals : frame.base+locals], nil
+}
+
+// Pop removes the top frame, along with its locals and evaluation stack
+func (stack *CallStack) Pop() (Frame, error) {
+	if len(stack.frames) == 0 {
+		return Frame{}, fmt.Errorf("%w: no routine to return from", ErrStackUnderflow)
+	}
+
+	frame := stack.frames[len(stack.frames)-1]
+	stack.frames = stack.frames[:len(stack.frames)-1]
+	stack.words = stack.words[:frame.base]
+	return frame, nil
+}
+
+// Peek returns the top frame, which stays valid until it's popped
+func (stack *CallStack) Peek() (*Frame, error) {
+	if len(stack.frames) == 0 {
+		return nil, fmt.Errorf("%w: no routine is running", ErrStackUnderflow)
+	}
+	return &stack.frames[len(stack.frames)-1], nil
+}
+
+// Reset removes every frame, keeping the arrays for reuse
+func (stack *CallStack) Reset() {
+	stack.frames = stack.frames[:0]
+	stack.words = stack.words[:0]
+}
+
+func (stack *CallStack) Size() int {
+	return len(stack.frames)
+}
+
+// Frame returns the frame at a depth, where 0 is the main routine
+func (stack *CallStack) Frame(i int) *Frame {
+	return &stack.frames[i]
+}
+
+// Locals returns the locals of the frame at a depth. Changing them changes the frame's locals.
+func (stack *CallStack) Locals(i int) []word {
+	frame := stack.frames[i]
+	return stack.words[frame.base : frame.base+frame.locals : frame.base+frame.locals]
+}
+
+// Values returns the evaluation stack of the frame at a depth, from the bottom up
+func (stack *CallStack) Values(i int) []word {
+	frame := stack.frames[i]
+	end := len(stack.words)
+	if i+1 < len(stack.frames) {
+		end = stack.frames[i+1].base
+	}
+	return stack.words[frame.base+frame.locals : end : end]
+}
+
+// Local reads a local of the top frame, where 0 is the first local
+func (stack *CallStack) Local(index int) (word, error) {
+	locals, err := stack.topLocals(index)
+	if err != nil {
+		return 0, err
+	}
+	return locals[index], nil
+}
+
+func (stack *CallStack) SetLocal(index int, value word) error {
+	locals, err := stack.topLocals(index)
+	if err != nil {
+		return err
+	}
+	locals[index] = value
+	return nil
+}
+
+func (stack *CallStack) topLocals(index int) ([]word, error) {
+	frame, err := stack.Peek()
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= frame.locals {
+		return nil, fmt.Errorf("Routine %x has no local%d, only %d locals", frame.Routine, index, frame.locals)
+	}
+	return stack.Locals(len(stack.frames) - 1), nil
+}
+
+// PushValue pushes onto the top frame's evaluation stack
+func (stack *CallStack) PushValue(value word) error {
+	frame, err := stack.Peek()
+	if err != nil {
+		return err
+	}
+	if len(stack.words) == cap(stack.words) {
+		return fmt.Errorf("%w: no room to push onto the stack of routine %x", ErrStackOverflow, frame.Routine)
+	}
+
+	stack.words = append(stack.words, value)
+	return nil
+}
+
+// PopValue pops from the top frame's evaluation stack, which can't reach into its locals
+func (stack *CallStack) PopValue() (word, error) {
+	top, err := stack.PeekValue()
+	if err != nil {
+		return 0, err
+	}
+
+	value := *top
+	stack.words = stack.words[:len(stack.words)-1]
+	return value, nil
+}
+
+// PeekValue returns the top of the top frame's evaluation stack, which can be changed in place
+func (stack *CallStack) PeekValue() (*word, error) {
+	frame, err := stack.Peek()
+	if err != nil {
+		return nil, err
+	}
+	if len(stack.words) == frame.base+frame.locals {
+		return nil, fmt.Errorf("%w: the stack of routine %x is empty", ErrStackUnderflow, frame.Routine)
+	}
+	return &stack.words[len(stack.words)-1], nil
+}
+
+// Clone copies the stack, for keeping its state when something goes wrong
+func (stack *CallStack) Clone() CallStack {
+	return CallStack{words: slices.Clone(stack.words), frames: slices.Clone(stack.frames)}
+}
+
+// CopyTo makes dst a copy of the stack, reusing dst's arrays so copying before every
+// instruction doesn't allocate
+func (stack *CallStack) CopyTo(dst *CallStack) {
+	dst.words = append(dst.words[:0], stack.words...)
+	dst.frames = append(dst.frames[:0], stack.frames...)
+}
@@ -0,0 +1,114 @@
+package zmachine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func TestCallStack_FramesShareOneArray(t *testing.T) {
+	stack := NewCallStack()
+
+	locals, err := stack.Push(Frame{}, 1)
+	testassert.NoError(t, err)
+	locals[0] = 9
+	testassert.NoError(t, stack.PushValue(1))
+	testassert.NoError(t, stack.PushValue(2))
+
+	locals, err = stack.Push(Frame{Routine: 0x400}, 2)
+	testassert.NoError(t, err)
+	copy(locals, []word{3, 4})
+	testassert.NoError(t, stack.PushValue(5))
+
+	testassert.True(t, slices.Equal([]word{9, 1, 2, 3, 4, 5}, stack.words))
+	testassert.True(t, slices.Equal([]word{9}, stack.Locals(0)))
+	testassert.True(t, slices.Equal([]word{1, 2}, stack.Values(0)))
+	testassert.True(t, slices.Equal([]word{3, 4}, stack.Locals(1)))
+	testassert.True(t, slices.Equal([]word{5}, stack.Values(1)))
+
+	frame, err := stack.Pop()
+	testassert.NoError(t, err)
+	testassert.Same(t, 0x400, frame.Routine)
+
+	value, err := stack.PopValue()
+	testassert.NoError(t, err)
+	testassert.Same(t, 2, value)
+}
+
+func TestCallStack_PushClearsLocals(t *testing.T) {
+	stack := NewCallStack()
+	stack.Push(Frame{}, 0)
+
+	locals, _ := stack.Push(Frame{}, 2)
+	copy(locals, []word{1, 2})
+	stack.Pop()
+
+	locals, _ = stack.Push(Frame{}, 2)
+	testassert.True(t, slices.Equal([]word{0, 0}, locals))
+}
+
+func TestCallStack_Underflow(t *testing.T) {
+	stack := NewCallStack()
+
+	_, err := stack.Pop()
+	testassert.True(t, errors.Is(err, ErrStackUnderflow))
+
+	locals, _ := stack.Push(Frame{Routine: 0x400}, 1)
+	locals[0] = 7
+
+	// The evaluation stack can't pop into the frame's locals
+	_, err = stack.PopValue()
+	testassert.ErrorMessage(t, "Stack underflow: the stack of routine 400 is empty", err)
+	_, err = stack.PeekValue()
+	testassert.True(t, errors.Is(err, ErrStackUnderflow))
+	testassert.Same(t, 7, stack.Locals(0)[0])
+}
+
+func TestCallStack_Overflow(t *testing.T) {
+	stack := NewCallStack()
+	for range MaxFrames {
+		_, err := stack.Push(Frame{}, 0)
+		testassert.NoError(t, err)
+	}
+	_, err := stack.Push(Frame{}, 0)
+	testassert.ErrorMessage(t, "Stack overflow: more than 1024 nested routine calls", err)
+
+	stack = NewCallStack()
+	_, err = stack.Push(Frame{}, StackWords-1)
+	testassert.NoError(t, err)
+	testassert.NoError(t, stack.PushValue(1))
+	err = stack.PushValue(2)
+	testassert.True(t, errors.Is(err, ErrStackOverflow))
+
+	_, err = stack.Push(Frame{Routine: 0x400}, 1)
+	testassert.ErrorMessage(t, "Stack overflow: no room for the locals of routine 400", err)
+}
+
+func TestCallStack_LocalOutOfRange(t *testing.T) {
+	stack := NewCallStack()
+	stack.Push(Frame{Routine: 0x400}, 2)
+
+	testassert.NoError(t, stack.SetLocal(1, 5))
+	value, err := stack.Local(1)
+	testassert.NoError(t, err)
+	testassert.Same(t, 5, value)
+
+	_, err = stack.Local(2)
+	testassert.ErrorMessage(t, "Routine 400 has no local2, only 2 locals", err)
+	testassert.ErrorMessage(t, "Routine 400 has no local2, only 2 locals", stack.SetLocal(2, 1))
+}
+
+func TestCallStack_CloneIsIndependent(t *testing.T) {
+	stack := NewCallStack()
+	stack.Push(Frame{}, 1)
+	stack.PushValue(3)
+
+	clone := stack.Clone()
+	stack.SetLocal(0, 9)
+	stack.PopValue()
+
+	testassert.Same(t, 0, clone.Locals(0)[0])
+	testassert.True(t, slices.Equal([]word{3}, clone.Values(0)))
+}
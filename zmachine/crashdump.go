@@ -0,0 +1,189 @@
+package zmachine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/quetzal"
+)
+
+const recentInstructionCount = 64
+
+// instructionRing keeps the most recently executed instructions for crash dumps
+type instructionRing struct {
+	instructions [recentInstructionCount]Instruction
+	next         int
+	full         bool
+}
+
+func (ring *instructionRing) add(instruction Instruction) {
+	if ring == nil {
+		return
+	}
+
+	// Variable operands are resolved in place once recorded, so keep the variable numbers
+	instruction.Operands = slices.Clone(instruction.Operands)
+	ring.instructions[ring.next] = instruction
+	ring.next = (ring.next + 1) % len(ring.instructions)
+	ring.full = ring.full || ring.next == 0
+}
+
+// list returns the instructions oldest first
+func (ring *instructionRing) list() []Instruction {
+	if ring == nil {
+		return nil
+	}
+	if !ring.full {
+		return ring.instructions[:ring.next]
+	}
+	return append(ring.instructions[ring.next:], ring.instructions[:ring.next]...)
+}
+
+// CrashDump records the state of a machine that stopped with an error, so it can be examined
+// and run again from the same point
+type CrashDump struct {
+	Story        string
+	Error        string
+	Header       DumpHeader
+	Instructions []DumpInstruction // Oldest first, ending with the one that failed
+	Frames       []DumpFrame       // Innermost last
+	Save         []byte            // Quetzal save from before the failed instruction, which resumes at it
+}
+
+type DumpHeader struct {
+	Version  int
+	Release  word
+	Serial   string
+	Checksum word
+	Bytes    []byte // The whole 64 byte header
+}
+
+type DumpInstruction struct {
+	PC   memory.Address
+	Text string
+}
+
+type DumpFrame struct {
+	Routine memory.Address
+	PC      memory.Address
+	Locals  []word
+	Stack   []word
+}
+
+// NewCrashDump captures the machine's state after err. The stack and memory are taken from the
+// error when it's a *ZMachineError, since that records them as they were before the instruction
+// that failed, so replaying the dump runs it again.
+func (zmachine *ZMachine) NewCrashDump(err error) (CrashDump, error) {
+	m := zmachine.Memory
+	serial := m.GetSerialCode()
+
+	// The dump may be replayed from another directory
+	story := m.GetPath()
+	if story != "" {
+		absolute, absErr := filepath.Abs(story)
+		if absErr != nil {
+			return CrashDump{}, absErr
+		}
+		story = absolute
+	}
+
+	dump := CrashDump{
+		Story: story,
+		Error: err.Error(),
+		Header: DumpHeader{
+			Version:  m.GetVersion(),
+			Release:  m.GetReleaseNumber(),
+			Serial:   string(serial[:]),
+			Checksum: m.GetChecksum(),
+			Bytes:    bytes.Clone(m.GetBytes(0, 64)),
+		},
+	}
+
+	for _, instruction := range zmachine.recent.list() {
+		dump.Instructions = append(dump.Instructions, DumpInstruction{PC: instruction.Address, Text: instruction.describe(zmachine.symbols)})
+	}
+
+	frames, dynamic := zmachine.Stack, m.GetDynamicMemory()
+	if zerr, ok := err.(*ZMachineError); ok {
+		frames, dynamic = zerr.Stack, zerr.Memory
+	}
+	for i := range frames.Size() {
+		frame := frames.Frame(i)
+		dump.Frames = append(dump.Frames, DumpFrame{
+			Routine: frame.Routine,
+			PC:      frame.Counter,
+			Locals:  slices.Clone(frames.Locals(i)),
+			Stack:   slices.Clone(frames.Values(i)),
+		})
+	}
+
+	save := bytes.Buffer{}
+	writeErr := zmachine.snapshotOf(&frames, dynamic).Write(&save, m.GetOriginalDynamicMemory())
+	if writeErr != nil {
+		return CrashDump{}, writeErr
+	}
+	dump.Save = save.Bytes()
+
+	return dump, nil
+}
+
+func (dump CrashDump) WriteFile(path string) error {
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func ReadCrashDump(path string) (CrashDump, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CrashDump{}, err
+	}
+
+	dump := CrashDump{}
+	err = json.Unmarshal(data, &dump)
+	return dump, err
+}
+
+// Summary describes the error, the instructions leading up to it and the frames on the stack
+func (dump CrashDump) Summary() string {
+	builder := bytes.Buffer{}
+	fmt.Fprintf(&builder, "%s (V%d release %d serial %s)\n%s\n\nRecent instructions:\n", dump.Story, dump.Header.Version,
+		dump.Header.Release, dump.Header.Serial, dump.Error)
+	for _, instruction := range dump.Instructions {
+		fmt.Fprintf(&builder, "  %x: %s\n", instruction.PC, instruction.Text)
+	}
+
+	builder.WriteString("\nStack:\n")
+	for i := len(dump.Frames) - 1; i >= 0; i-- {
+		frame := dump.Frames[i]
+		fmt.Fprintf(&builder, "  #%d routine %x pc %x locals %x stack %x\n", i, frame.Routine, frame.PC, frame.Locals, frame.Stack)
+	}
+	return builder.String()
+}
+
+// RestoreCrashDump puts the machine back into the state recorded in a dump, ready to run the
+// instruction that failed
+func (zmachine *ZMachine) RestoreCrashDump(dump CrashDump) error {
+	save, err := quetzal.Read(bytes.NewReader(dump.Save), zmachine.Memory.GetOriginalDynamicMemory())
+	if err != nil {
+		return err
+	}
+
+	err = zmachine.restoreSnapshot(save)
+	if err != nil {
+		return err
+	}
+
+	// Frames only record routine addresses in the dump, since Quetzal has no place for them
+	for i := range min(len(dump.Frames), zmachine.Stack.Size()) {
+		zmachine.Stack.Frame(i).Routine = dump.Frames[i].Routine
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package zmachine
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func TestInstructionRing_KeepsMostRecent(t *testing.T) {
+	ring := &instructionRing{}
+	for i := range recentInstructionCount + 3 {
+		ring.add(Instruction{Address: memory.Address(i)})
+	}
+
+	instructions := ring.list()
+	testassert.Same(t, recentInstructionCount, len(instructions))
+	testassert.Same(t, memory.Address(3), instructions[0].Address)
+	testassert.Same(t, memory.Address(recentInstructionCount+2), instructions[len(instructions)-1].Address)
+}
+
+func TestCrashDump_RoundTrip(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	address := memory.Address(0x100)
+	zmachine.Memory.WriteWord(address, 0xbeef)
+
+	zerr := zmachine.newError(0x5001, errors.New("Something broke"))
+	dump, err := zmachine.NewCrashDump(zerr)
+	testassert.NoError(t, err)
+	testassert.Same(t, 3, dump.Header.Version)
+	testassert.Same(t, 64, len(dump.Header.Bytes))
+	testassert.Same(t, 2, len(dump.Frames))
+	testassert.True(t, filepath.IsAbs(dump.Story))
+
+	path := filepath.Join(t.TempDir(), "test.crash.json")
+	testassert.NoError(t, dump.WriteFile(path))
+	loaded, err := ReadCrashDump(path)
+	testassert.NoError(t, err)
+	testassert.Same(t, dump.Error, loaded.Error)
+
+	zmachine.Memory.WriteWord(address, 0x1234)
+	zmachine.Stack.Pop()
+
+	testassert.NoError(t, zmachine.RestoreCrashDump(loaded))
+	testassert.Same(t, 0xbeef, zmachine.Memory.ReadWord(address))
+	testassert.Same(t, 2, zmachine.Stack.Size())
+	testassert.Same(t, memory.Address(0x5001), zmachine.Stack.Frame(1).Counter)
+	testassert.True(t, slices.Equal([]word{1, 2, 3}, zmachine.Stack.Locals(1)))
+}
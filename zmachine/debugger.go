@@ -0,0 +1,392 @@
+package zmachine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Debugger runs a story one instruction at a time from commands typed at a prompt, so the state
+// leading up to an error can be examined. The story draws to a simulated screen, with the lower
+// window's text written to the debugger's output, and its input is given with the input command.
+type Debugger struct {
+	zmachine    *ZMachine
+	sim         tcell.SimulationScreen
+	in          *bufio.Scanner
+	out         *lineWriter
+	breakpoints map[memory.Address]bool
+	input       []string // Lines for read, or keys for read_char, given ahead of the story asking
+	stopped     error    // Why the story can't run any further
+}
+
+// lineWriter notes whether the last thing written ended a line, so the debugger can start its
+// own output on a new line after the story's text
+type lineWriter struct {
+	io.Writer
+	midLine bool
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.midLine = p[len(p)-1] != '\n'
+	}
+	return w.Writer.Write(p)
+}
+
+func (w *lineWriter) endLine() {
+	if w.midLine {
+		fmt.Fprintln(w)
+	}
+}
+
+type debuggerCommand struct {
+	usage       string
+	description string
+	run         func(d *Debugger, args []string) error
+}
+
+var debuggerCommands map[string]debuggerCommand
+
+// Shorter names for the commands used most
+var debuggerAliases = map[string]string{
+	"s":  "step",
+	"c":  "continue",
+	"b":  "break",
+	"bt": "stack",
+	"p":  "print",
+	"x":  "memory",
+	"q":  "quit",
+}
+
+func init() {
+	debuggerCommands = map[string]debuggerCommand{
+		"step":     {"step [n]", "Run the next instruction, or the next n", debugStep},
+		"continue": {"continue", "Run until a breakpoint, an error or the story asks for input", debugContinue},
+		"break":    {"break <addr>", "Stop before running the instruction at a hex address", debugBreak},
+		"delete":   {"delete <addr>", "Remove a breakpoint", debugDelete},
+		"input":    {"input [text]", "Give the story a line to read, or a key for read_char", debugInput},
+		"stack":    {"stack", "List the frames on the stack, innermost first", debugStack},
+		"print":    {"print <var>", "Show a variable: sp, local0 to local14 or g0 to g239", debugPrint},
+		"memory":   {"memory <addr> [n]", "Show n bytes of memory from a hex address", debugMemory},
+		"help":     {"help", "List these commands", debugHelp},
+	}
+}
+
+// NewDebugger takes over a machine running on a simulated screen, which the debugger presses
+// keys on for read_char
+func NewDebugger(zmachine *ZMachine, sim tcell.SimulationScreen, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{
+		zmachine:    zmachine,
+		sim:         sim,
+		in:          bufio.NewScanner(in),
+		out:         &lineWriter{Writer: out},
+		breakpoints: map[memory.Address]bool{},
+	}
+	zmachine.Screen.Transcript = d.out
+	zmachine.Screen.Paging = false
+	zmachine.exit = func(code int) { d.stopped = errors.New("The story has quit") }
+	return d
+}
+
+// Run shows the next instruction and carries out commands until quit or the end of input
+func (d *Debugger) Run() {
+	d.showNext()
+	for {
+		fmt.Fprint(d.out, "(zdb) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			return
+		}
+
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		if alias, ok := debuggerAliases[name]; ok {
+			name = alias
+		}
+		if name == "quit" {
+			return
+		}
+
+		command, ok := debuggerCommands[name]
+		if !ok {
+			fmt.Fprintf(d.out, "Unknown command %q, try help\n", fields[0])
+			continue
+		}
+
+		err := command.run(d, fields[1:])
+		if err != nil {
+			fmt.Fprintln(d.out, err)
+		}
+	}
+}
+
+// step runs one instruction, reporting false when the debugger should stop before the next
+func (d *Debugger) step() bool {
+	if d.stopped != nil {
+		fmt.Fprintln(d.out, d.stopped)
+		return false
+	}
+
+	zmachine := d.zmachine
+	if !d.takeInput() {
+		fmt.Fprintln(d.out, "The story is waiting for input, give it with: input [text]")
+		return false
+	}
+
+	err := zmachine.runSoundRoutines()
+	if err == nil {
+		err = zmachine.executeNextInstruction()
+	}
+	if err != nil {
+		err = zmachine.handleError(err)
+	}
+	if err != nil {
+		d.stopped = fmt.Errorf("The story stopped with an error, so it can only be examined: %w", err)
+		d.out.endLine()
+		fmt.Fprintln(d.out, err)
+		zerr := &ZMachineError{}
+		if errors.As(err, &zerr) {
+			fmt.Fprint(d.out, zerr.StackTrace())
+		}
+		return false
+	}
+	if d.stopped != nil {
+		fmt.Fprintln(d.out, d.stopped)
+		return false
+	}
+	return true
+}
+
+// takeInput hands the next input to a read or read_char that's about to run, reporting false
+// when there's none to give
+func (d *Debugger) takeInput() bool {
+	instruction, ok := d.next()
+	if !ok || (instruction.Name() != "read" && instruction.Name() != "read_char") {
+		return true
+	}
+	if len(d.input) == 0 {
+		return false
+	}
+
+	text := d.input[0]
+	d.input = d.input[1:]
+	if instruction.Name() == "read" {
+		d.zmachine.script = append(d.zmachine.script, text)
+		return true
+	}
+
+	if text == "" {
+		d.sim.InjectKey(tcell.KeyEnter, '\r', tcell.ModNone)
+	} else {
+		d.sim.InjectKey(tcell.KeyRune, []rune(text)[0], tcell.ModNone)
+	}
+	return true
+}
+
+func (d *Debugger) next() (Instruction, bool) {
+	frame, err := d.zmachine.Stack.Peek()
+	if err != nil {
+		return Instruction{}, false
+	}
+	instruction, _, err := d.zmachine.decodeInstruction(frame.Counter)
+	return instruction, err == nil
+}
+
+func (d *Debugger) counter() memory.Address {
+	frame, err := d.zmachine.Stack.Peek()
+	if err != nil {
+		return 0
+	}
+	return frame.Counter
+}
+
+func (d *Debugger) showNext() {
+	d.out.endLine()
+	if d.stopped != nil {
+		return
+	}
+
+	pc := d.counter()
+	instruction, ok := d.next()
+	if !ok {
+		fmt.Fprintf(d.out, "%s: not an instruction\n", describeAddress(d.zmachine.symbols, pc))
+		return
+	}
+	fmt.Fprintf(d.out, "%s: %s\n", describeAddress(d.zmachine.symbols, pc), instruction.describe(d.zmachine.symbols))
+}
+
+func debugStep(d *Debugger, args []string) error {
+	count := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return errors.New("Usage: " + debuggerCommands["step"].usage)
+		}
+		count = n
+	}
+
+	for range count {
+		if !d.step() {
+			break
+		}
+	}
+	d.showNext()
+	return nil
+}
+
+func debugContinue(d *Debugger, args []string) error {
+	for d.step() {
+		if d.breakpoints[d.counter()] {
+			fmt.Fprintln(d.out, "Breakpoint")
+			break
+		}
+	}
+	d.showNext()
+	return nil
+}
+
+func debugBreak(d *Debugger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: " + debuggerCommands["break"].usage)
+	}
+	address, err := parseHexAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	d.breakpoints[address] = true
+	return nil
+}
+
+func debugDelete(d *Debugger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: " + debuggerCommands["delete"].usage)
+	}
+	address, err := parseHexAddress(args[0])
+	if err != nil {
+		return err
+	}
+	if !d.breakpoints[address] {
+		return fmt.Errorf("There's no breakpoint at %x", address)
+	}
+
+	delete(d.breakpoints, address)
+	return nil
+}
+
+func debugInput(d *Debugger, args []string) error {
+	d.input = append(d.input, strings.Join(args, " "))
+	return nil
+}
+
+func debugStack(d *Debugger, args []string) error {
+	stack := &d.zmachine.Stack
+	for i := stack.Size() - 1; i >= 0; i-- {
+		frame := stack.Frame(i)
+		fmt.Fprintf(d.out, "#%d routine %x pc %s locals %x stack %x\n", i, frame.Routine,
+			describeAddress(d.zmachine.symbols, frame.Counter), stack.Locals(i), stack.Values(i))
+	}
+	return nil
+}
+
+func debugPrint(d *Debugger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: " + debuggerCommands["print"].usage)
+	}
+	varnum, err := parseVarNum(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Reading sp in place leaves the stack as the story left it
+	variable := d.zmachine.getVariable(varnum)
+	value, err := variable.ReadInPlace()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(d.out, "%s = %04x (%d)\n", varnum, value, int16(value))
+	return nil
+}
+
+func debugMemory(d *Debugger, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("Usage: " + debuggerCommands["memory"].usage)
+	}
+	address, err := parseHexAddress(args[0])
+	if err != nil {
+		return err
+	}
+	if int(address) >= d.zmachine.Memory.Size() {
+		return fmt.Errorf("%x is past the end of the story", address)
+	}
+	length := 16
+	if len(args) == 2 {
+		length, err = strconv.Atoi(args[1])
+		if err != nil || length < 1 {
+			return errors.New("Usage: " + debuggerCommands["memory"].usage)
+		}
+	}
+
+	length = min(length, d.zmachine.Memory.Size()-int(address))
+	data := d.zmachine.Memory.GetBytes(address, length)
+	for line := range slices.Chunk(data, 16) {
+		fmt.Fprintf(d.out, "%05x: % x\n", address, line)
+		address = address.OffsetBytes(len(line))
+	}
+	return nil
+}
+
+func debugHelp(d *Debugger, args []string) error {
+	names := make([]string, 0, len(debuggerCommands))
+	for name := range debuggerCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		command := debuggerCommands[name]
+		fmt.Fprintf(d.out, "%-18s %s\n", command.usage, command.description)
+	}
+	fmt.Fprintf(d.out, "%-18s %s\n", "quit", "Leave the debugger")
+	return nil
+}
+
+func parseHexAddress(text string) (memory.Address, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(text), "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a hex address", text)
+	}
+	return memory.Address(value), nil
+}
+
+// parseVarNum reads a variable named as instructions are described
+func parseVarNum(text string) (VarNum, error) {
+	text = strings.ToLower(text)
+	if text == "sp" {
+		return StackVarNum, nil
+	}
+
+	if n, ok := strings.CutPrefix(text, "local"); ok {
+		index, err := strconv.Atoi(n)
+		if err == nil && index >= 0 && index <= int(MaxLocalVarNum-MinLocalVarNum) {
+			return MinLocalVarNum + VarNum(index), nil
+		}
+	} else if n, ok := strings.CutPrefix(text, "g"); ok {
+		index, err := strconv.Atoi(n)
+		if err == nil && index >= 0 && index <= int(MaxGlobalVarNum-MinGlobalVarNum) {
+			return MinGlobalVarNum + VarNum(index), nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a variable, use sp, local0 to local14 or g0 to g239", text)
+}
@@ -0,0 +1,68 @@
+package zmachine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/screen"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+)
+
+func runDebugger(t *testing.T, story teststory.Story, commands ...string) (*ZMachine, string) {
+	t.Helper()
+
+	m, err := story.Memory()
+	testassert.NoError(t, err)
+	s, sim := screen.NewSimulationScreen(80, 25)
+	zmachine, err := NewZMachine(m, s)
+	testassert.NoError(t, err)
+	zmachine.ErrorLevel = EL_Halt
+
+	output := &bytes.Buffer{}
+	NewDebugger(zmachine, sim, strings.NewReader(strings.Join(commands, "\n")), output).Run()
+	return zmachine, output.String()
+}
+
+func TestDebugger_StepsAndExamines(t *testing.T) {
+	story := teststory.Story{Main: []teststory.Instruction{
+		storeGlobal(g0, 5),
+		teststory.Op0(0x02).Text("hi"),
+		teststory.Op2(0x17, teststory.Var(g0), teststory.Small(0)).Store(g1),
+	}}
+
+	zmachine, output := runDebugger(t, story, "step", "print g0", "s", "x 0 2", "continue", "step", "bt")
+
+	testassert.True(t, strings.Contains(output, "STORE #10 #05"))
+	testassert.True(t, strings.Contains(output, "g0 = 0005 (5)"))
+	testassert.True(t, strings.Contains(output, "hi"))
+	testassert.True(t, strings.Contains(output, "00000: 03 00"))
+	testassert.True(t, strings.Contains(output, "Division by zero"))
+	testassert.True(t, strings.Contains(output, "The story stopped with an error, so it can only be examined"))
+	testassert.True(t, strings.Contains(output, "#0 routine 0"))
+
+	value, err := zmachine.getVariable(VarNum(g0)).Read()
+	testassert.NoError(t, err)
+	testassert.Same(t, 5, value)
+}
+
+func TestDebugger_BreakpointsAndInput(t *testing.T) {
+	story := teststory.Story{Version: 4, Main: []teststory.Instruction{
+		teststory.OpVar(0x16, teststory.Small(1)).Store(g0),
+		storeGlobal(g1, 1),
+		storeGlobal(g2, 2),
+	}}
+	m, err := story.Memory()
+	testassert.NoError(t, err)
+	third := m.GetInitialProgramCounter().OffsetBytes(4 + 3)
+
+	_, output := runDebugger(t, story, "continue", "input x", fmt.Sprintf("break %x", third), "c", "print g0", "print g1", "print g2")
+
+	testassert.True(t, strings.Contains(output, "The story is waiting for input"))
+	testassert.True(t, strings.Contains(output, "Breakpoint"))
+	testassert.True(t, strings.Contains(output, "g0 = 0078 (120)"))
+	testassert.True(t, strings.Contains(output, "g1 = 0001 (1)"))
+	testassert.True(t, strings.Contains(output, "g2 = 0000 (0)"))
+}
@@ -0,0 +1,93 @@
+package zmachine
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+)
+
+// Inform records what a word can be used as in the first byte of data after the encoded text
+const (
+	DictFlag_Verb byte = 0x01
+	DictFlag_Noun byte = 0x80
+)
+
+type Dictionary struct {
+	mem          *memory.Memory
+	Separators   []zstring.ZSCII
+	EntryLength  int
+	EntryCount   int
+	Sorted       bool
+	entriesAddr  memory.Address
+	encodedBytes int
+}
+
+func GetDictionary(mem *memory.Memory, address memory.Address) Dictionary {
+	separatorCount, nextAddress := mem.ReadByteNext(address)
+	separatorBytes, nextAddress := mem.GetBytesNext(nextAddress, int(separatorCount))
+	entryLength, nextAddress := mem.ReadByteNext(nextAddress)
+	entryCount, nextAddress := mem.ReadWordNext(nextAddress)
+
+	separators := make([]zstring.ZSCII, 0, separatorCount)
+	for _, b := range separatorBytes {
+		separators = append(separators, zstring.ZSCII(b))
+	}
+
+	// A negative entry count indicates the entries are unsorted
+	count := int(int16(entryCount))
+	sorted := count >= 0
+	if !sorted {
+		count = -count
+	}
+
+	return Dictionary{
+		mem:          mem,
+		Separators:   separators,
+		EntryLength:  int(entryLength),
+		EntryCount:   count,
+		Sorted:       sorted,
+		entriesAddr:  nextAddress,
+		encodedBytes: zstring.GetDictionaryWordLength(mem.GetVersion()) * 2 / 3,
+	}
+}
+
+func (d Dictionary) EntryAddress(index int) memory.Address {
+	return d.entriesAddr.OffsetBytes(index * d.EntryLength)
+}
+
+func (d Dictionary) EncodedWord(index int) zstring.ZString {
+	return d.mem.GetBytes(d.EntryAddress(index), d.encodedBytes)
+}
+
+// Flags returns the Inform flag byte of an entry, or 0 if entries are too short to hold one
+func (d Dictionary) Flags(index int) byte {
+	if d.EntryLength <= d.encodedBytes {
+		return 0
+	}
+	return d.mem.ReadByte(d.EntryAddress(index).OffsetBytes(d.encodedBytes))
+}
+
+// Lookup returns the address of the entry matching the encoded word, or 0 if there isn't one.
+// Sorted dictionaries are binary searched, since their entries are in numerical order of their
+// encoded text.
+func (d Dictionary) Lookup(encoded zstring.ZString) memory.Address {
+	if d.Sorted {
+		i := sort.Search(d.EntryCount, func(i int) bool {
+			return bytes.Compare(d.EncodedWord(i), encoded) >= 0
+		})
+		if i < d.EntryCount && bytes.Equal(d.EncodedWord(i), encoded) {
+			return d.EntryAddress(i)
+		}
+		return 0
+	}
+
+	for i := range d.EntryCount {
+		if bytes.Equal(d.EncodedWord(i), encoded) {
+			return d.EntryAddress(i)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,41 @@
+package zmachine
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+)
+
+func TestDictionary_Lookup(t *testing.T) {
+	words := []string{"west", "take", "lamp", "north", "east", "drop", "zzz"}
+
+	for _, unsorted := range []bool{false, true} {
+		story := teststory.Story{Dictionary: teststory.Dictionary{Words: words, Unsorted: unsorted}}
+		m, err := story.Memory()
+		testassert.NoError(t, err)
+
+		dictionary := GetDictionary(m, m.GetDictionaryAddress())
+		testassert.Same(t, !unsorted, dictionary.Sorted)
+		testassert.Same(t, len(words), dictionary.EntryCount)
+
+		charset, _ := zstring.NewStaticCharset(zstring.GetDefaultAlphabet(3), zstring.GetDefaultCtrlCharMapping(3))
+		encode := func(text string) zstring.ZString {
+			encoded, err := zstring.EncodeWord([]rune(text), charset, zstring.GetDefaultUnicodeTable(3), 3)
+			testassert.NoError(t, err)
+			return encoded
+		}
+
+		for _, text := range words {
+			address := dictionary.Lookup(encode(text))
+			testassert.True(t, address != 0)
+			testassert.Same(t, string(encode(text)), string(m.GetBytes(address, 4)))
+		}
+
+		for _, text := range []string{"a", "lantern", "zzzz", ""} {
+			testassert.Same(t, memory.Address(0), dictionary.Lookup(encode(text)))
+		}
+	}
+}
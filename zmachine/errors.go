@@ -0,0 +1,132 @@
+package zmachine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+// ErrorLevel chooses how runtime errors are handled, following the levels of Frotz's -Z option
+type ErrorLevel int
+
+const (
+	EL_Ignore     ErrorLevel = 0 // Carry on without reporting errors
+	EL_ReportOnce ErrorLevel = 1 // Report the first occurrence of each error, then carry on
+	EL_ReportAll  ErrorLevel = 2 // Report every error, then carry on
+	EL_Halt       ErrorLevel = 3 // Stop at the first error
+)
+
+// ErrDivisionByZero is returned by div and mod when the divisor is 0
+var ErrDivisionByZero = errors.New("Division by zero")
+
+// ZMachineError describes a failure while executing an instruction, along with the state of the
+// machine when it happened
+type ZMachineError struct {
+	PC      memory.Address
+	Opcode  Opcode
+	Routine memory.Address // Address of the running routine, or 0 for the main routine
+	Stack   CallStack      // Copy of the call stack from before the instruction ran
+	Memory  []byte         // Copy of dynamic memory from the same moment as the stack
+	Fatal   bool           // The instruction couldn't be decoded, so there's no way to carry on past it
+	Err     error
+	next    memory.Address
+	store   *Variable // Where the failed instruction stores its result, if it does
+	depth   int
+	symbols *symbolTable
+	done    bool // The instruction finished anyway, so there's nothing to skip
+}
+
+func (e *ZMachineError) Error() string {
+	return fmt.Sprintf("%v (pc %x, opcode %02x, routine %x)", e.Err, e.PC, e.Opcode, e.Routine)
+}
+
+func (e *ZMachineError) Unwrap() error {
+	return e.Err
+}
+
+// StackTrace lists the frames on the stack, innermost first, with their locals and stacks
+func (e *ZMachineError) StackTrace() string {
+	builder := strings.Builder{}
+	for i := e.Stack.Size() - 1; i >= 0; i-- {
+		frame := e.Stack.Frame(i)
+		fmt.Fprintf(&builder, "#%d routine %x pc %s locals %x stack %x\n", i, frame.Routine,
+			describeAddress(e.symbols, frame.Counter), e.Stack.Locals(i), e.Stack.Values(i))
+	}
+	return builder.String()
+}
+
+// finishedError is returned by a handler that reports an error but still finishes the
+// instruction, the way Frotz carries on past it, so the instruction isn't skipped
+type finishedError struct {
+	err error
+}
+
+func (e *finishedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *finishedError) Unwrap() error {
+	return e.err
+}
+
+func finished(err error) error {
+	return &finishedError{err}
+}
+
+// newError wraps a failure in the instruction at pc with a snapshot of the machine
+func (zmachine *ZMachine) newError(pc memory.Address, err error) *ZMachineError {
+	e := &ZMachineError{PC: pc, Stack: zmachine.Stack.Clone(), Memory: zmachine.Memory.GetDynamicMemory(), Err: err,
+		depth: zmachine.Stack.Size(), symbols: zmachine.symbols}
+	if top, err := zmachine.Stack.Peek(); err == nil {
+		e.Routine = top.Routine
+	}
+	return e
+}
+
+// peekOpcode reads the opcode at an address for error reports, where the address may not even
+// hold a valid instruction
+func (zmachine *ZMachine) peekOpcode(address memory.Address) (opcode Opcode) {
+	defer func() {
+		if recover() != nil {
+			opcode = 0
+		}
+	}()
+
+	opcode, _ = zmachine.readOpcode(address)
+	return opcode
+}
+
+// handleError applies the error level, returning the error if execution should stop. When it
+// carries on, the instruction that failed is skipped. As in Frotz, a skipped instruction stores 0
+// and doesn't branch, which keeps the stack balanced when it would have pushed its result.
+func (zmachine *ZMachine) handleError(err error) error {
+	zerr := &ZMachineError{}
+	if !errors.As(err, &zerr) || zerr.Fatal || zmachine.ErrorLevel >= EL_Halt {
+		return err
+	}
+
+	message := zerr.Err.Error()
+	report := zmachine.ErrorLevel == EL_ReportAll || (zmachine.ErrorLevel == EL_ReportOnce && !zmachine.reported[message])
+	if report {
+		zmachine.Screen.PrintText(fmt.Sprintf("\n[Z-Machine error: %s]\n", zerr))
+	}
+	if zmachine.reported == nil {
+		zmachine.reported = map[string]bool{}
+	}
+	zmachine.reported[message] = true
+	if zerr.done {
+		return nil
+	}
+
+	// Carry on from the next instruction of the routine that failed, if it's still running
+	if zerr.depth > 0 && zerr.depth <= zmachine.Stack.Size() {
+		zmachine.Stack.Frame(zerr.depth - 1).Counter = zerr.next
+		if zerr.store != nil && zerr.depth == zmachine.Stack.Size() {
+			// There's nothing more to do if even this fails, such as when the stack is full
+			zerr.store.Write(0)
+		}
+	}
+	return nil
+}
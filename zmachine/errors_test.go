@@ -0,0 +1,159 @@
+package zmachine
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/screen"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestExecuteNextInstruction_ReturnsZMachineError(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+
+	// 0x00 is not an instruction in any version, so it can't be decoded
+	zmachine.Stack.Frame(1).Counter = 0x100
+	zmachine.Memory.WriteByte(0x100, 0x00)
+	zmachine.Stack.Frame(1).Routine = 0xf0
+
+	err := zmachine.executeNextInstruction()
+
+	zerr := &ZMachineError{}
+	testassert.True(t, errors.As(err, &zerr))
+	testassert.Same(t, memory.Address(0x100), zerr.PC)
+	testassert.Same(t, memory.Address(0xf0), zerr.Routine)
+	testassert.Same(t, 2, zerr.Stack.Size())
+	testassert.True(t, zerr.Fatal)
+	testassert.ErrorMessage(t, "unknown opcode: 00", zerr.Err)
+}
+
+func TestExecuteNextInstruction_ErrorKeepsStateFromBefore(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	testassert.NoError(t, zmachine.Stack.PushValue(9))
+
+	// div sp #00 -> g0, which pops its operand before finding it can't divide
+	zmachine.Memory.SetBytes(0x100, []byte{0x57, 0x00, 0x00, 0x10})
+	zmachine.Stack.Frame(1).Counter = 0x100
+
+	err := zmachine.executeNextInstruction()
+
+	zerr := &ZMachineError{}
+	testassert.True(t, errors.As(err, &zerr))
+	testassert.True(t, errors.Is(err, ErrDivisionByZero))
+	testassert.Same(t, 0, len(zmachine.Stack.Values(1)))
+	testassert.Same(t, 1, len(zerr.Stack.Values(1)))
+	testassert.Same(t, 9, zerr.Stack.Values(1)[0])
+	testassert.Same(t, memory.Address(0x100), zerr.Stack.Frame(1).Counter)
+	testassert.Same(t, 0x57, zerr.Memory[0x100])
+}
+
+func TestHandleError_Levels(t *testing.T) {
+	type spec struct {
+		level  ErrorLevel
+		fatal  bool
+		halted bool
+	}
+
+	tests := map[string]spec{
+		"halt":          {level: EL_Halt, halted: true},
+		"ignore":        {level: EL_Ignore, halted: false},
+		"ignore fatal":  {level: EL_Ignore, fatal: true, halted: true},
+		"report once":   {level: EL_ReportOnce, halted: false},
+		"not a zmerror": {level: EL_Ignore, halted: true},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			zmachine := newSaveTestMachine(t)
+			zmachine.ErrorLevel = s.level
+			zmachine.reported = map[string]bool{"bad attribute": true} // Already reported, so nothing is printed
+
+			var err error = &ZMachineError{Err: errors.New("bad attribute"), Fatal: s.fatal, next: 0x5005, depth: 2}
+			if name == "not a zmerror" {
+				err = errors.New("bad attribute")
+			}
+
+			result := zmachine.handleError(err)
+			testassert.Same(t, s.halted, result != nil)
+			if !s.halted {
+				// Execution carries on after the failed instruction
+				testassert.Same(t, memory.Address(0x5005), zmachine.Stack.Frame(1).Counter)
+			}
+		})
+	}
+}
+
+func TestHandleError_SkippedInstructionStoresZero(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	zmachine.ErrorLevel = EL_Ignore
+
+	// load sp -> sp, where the routine's stack is empty
+	zmachine.Memory.SetBytes(0x100, []byte{0x9e, 0x00, 0x00})
+	zmachine.Stack.Frame(1).Counter = 0x100
+
+	err := zmachine.executeNextInstruction()
+	testassert.ErrorMessage(t, "Stack underflow: the stack of routine 0 is empty", errors.Unwrap(err))
+	testassert.NoError(t, zmachine.handleError(err))
+
+	// The result is pushed as 0, so the stack stays balanced for the instructions that follow
+	testassert.Same(t, memory.Address(0x103), zmachine.Stack.Frame(1).Counter)
+	testassert.Same(t, 1, len(zmachine.Stack.Values(1)))
+	testassert.Same(t, 0, zmachine.Stack.Values(1)[0])
+}
+
+func TestInstruction_End_SkipsInlineText(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+
+	// print, followed by two words of text
+	zmachine.Memory.SetBytes(0x100, []byte{0xb2, 0x12, 0x34, 0x92, 0x34})
+
+	instruction, _, err := zmachine.readInstruction(0x100)
+	testassert.NoError(t, err)
+	testassert.Same(t, memory.Address(0x101), instruction.NextAddress)
+	testassert.Same(t, memory.Address(0x105), instruction.end(zmachine.Memory))
+}
+
+func TestRun_LeavesStateOfFailure(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	zmachine.ErrorLevel = EL_Halt
+
+	// push #05, then an instruction that can't be decoded
+	zmachine.Memory.SetBytes(0x100, []byte{0xe8, 0x7f, 0x05, 0x00})
+	zmachine.Stack.Frame(1).Counter = 0x100
+
+	err := zmachine.Run()
+	testassert.ErrorMessage(t, "unknown opcode: 00", errors.Unwrap(err))
+
+	// Crash dumps are taken from the machine once Run returns, so it must see the push
+	testassert.Same(t, memory.Address(0x103), zmachine.Stack.Frame(1).Counter)
+	testassert.Same(t, 1, len(zmachine.Stack.Values(1)))
+	testassert.Same(t, 5, zmachine.Stack.Values(1)[0])
+}
+
+func TestRun_QuittingAtPromptShutsDown(t *testing.T) {
+	story := teststory.Story{Version: 4, Main: []teststory.Instruction{
+		teststory.OpVar(0x16, teststory.Small(1)).Store(g0),
+	}}
+	m, err := story.Memory()
+	testassert.NoError(t, err)
+	s, sim := screen.NewSimulationScreen(80, 25)
+	zmachine, err := NewZMachine(m, s)
+	testassert.NoError(t, err)
+
+	profile := bytes.Buffer{}
+	zmachine.Profiler = NewProfiler(m, &profile, PF_Text)
+	exit := -1
+	zmachine.exit = func(code int) { exit = code }
+
+	sim.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	testassert.NoError(t, zmachine.Run())
+
+	// Shutting down writes the profile, which quitting straight from the screen used to lose
+	testassert.Same(t, 0, exit)
+	testassert.True(t, strings.Contains(profile.String(), "(main)"))
+}
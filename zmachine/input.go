@@ -0,0 +1,207 @@
+package zmachine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/screen"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+)
+
+type token struct {
+	start int
+	text  []zstring.ZSCII
+}
+
+// timedInput converts the time (in tenths of a second) and routine operands of read and read_char
+// into an interrupt for the screen. The interrupt ends input if the routine returns true, or if
+// the routine fails, in which case the error is stored in errp.
+func (zmachine *ZMachine) timedInput(tenths Operand, routine Operand, errp *error) (time.Duration, func() bool) {
+	if tenths.asWord() == 0 || routine.asWord() == 0 {
+		return 0, nil
+	}
+
+	interval := time.Duration(tenths.asWord()) * 100 * time.Millisecond
+	interrupt := func() bool {
+		result, err := zmachine.callRoutine(routine.asWord())
+		if err != nil {
+			*errp = err
+			return true
+		}
+		return result != 0
+	}
+
+	return interval, interrupt
+}
+
+// nextInput reads a line from the screen, or takes the next line of a script being replayed
+func (zmachine *ZMachine) nextInput(options screen.ReadOptions) (string, zstring.ZSCII, error) {
+	if len(zmachine.script) == 0 {
+		return zmachine.Screen.Read(options)
+	}
+
+	line := zmachine.script[0]
+	zmachine.script = zmachine.script[1:]
+
+	// Echo the line, along with any preloaded text, as though it were typed. Only what could have
+	// been typed is kept.
+	runes := make([]rune, 0, len(line))
+	for _, r := range options.Preload + line {
+		if options.Accept == nil || options.Accept(r) {
+			runes = append(runes, r)
+		}
+	}
+	if options.MaxLength > 0 {
+		runes = runes[:min(len(runes), options.MaxLength)]
+	}
+
+	zmachine.Screen.PrintText(string(runes))
+	zmachine.Screen.Flush()
+	return string(runes), zstring.ZSCII_NewLine, nil
+}
+
+func (zmachine *ZMachine) acceptsInput(r rune) bool {
+	zc, err := zmachine.Unicode.ToZSCII(r)
+	return err == nil && zc != zstring.ZSCII_NewLine
+}
+
+// toInputZSCII converts typed text to lowercase ZSCII, dropping anything unrepresentable
+func (zmachine *ZMachine) toInputZSCII(str string) []zstring.ZSCII {
+	input := make([]zstring.ZSCII, 0, len(str))
+	for _, r := range str {
+		zc, err := zmachine.Unicode.ToZSCII(r)
+		if err != nil {
+			continue
+		}
+		input = append(input, zmachine.Unicode.ToLower(zc))
+	}
+
+	return input
+}
+
+func (zmachine *ZMachine) toUnicode(text []zstring.ZSCII) string {
+	runes := make([]rune, 0, len(text))
+	for _, zc := range text {
+		r, err := zmachine.Unicode.ToRune(zc)
+		if err != nil {
+			continue
+		}
+		runes = append(runes, r)
+	}
+
+	return string(runes)
+}
+
+// isTerminatingCharacter checks the story's terminating characters table, where 255 stands in
+// for every function key
+func (zmachine *ZMachine) isTerminatingCharacter(zc zstring.ZSCII) bool {
+	address := zmachine.Memory.GetTerminatingCharactersAddress()
+	if zmachine.Memory.GetVersion() < 5 || address == 0 {
+		return false
+	}
+
+	for b := zmachine.Memory.ReadByte(address); b != 0; b = zmachine.Memory.ReadByte(address) {
+		if zstring.ZSCII(b) == zc || (b == 255 && zc.IsFunctionKey()) {
+			return true
+		}
+		address = address.OffsetBytes(1)
+	}
+
+	return false
+}
+
+func splitWords(text []zstring.ZSCII, separators []zstring.ZSCII) []token {
+	tokens := make([]token, 0)
+	start := -1
+
+	for i, zc := range text {
+		isSpace := zc == ' '
+		isSeparator := slices.Contains(separators, zc)
+
+		if (isSpace || isSeparator) && start >= 0 {
+			tokens = append(tokens, token{start: start, text: text[start:i]})
+			start = -1
+		}
+
+		if isSeparator {
+			// Word separators are words in their own right
+			tokens = append(tokens, token{start: i, text: text[i : i+1]})
+		} else if !isSpace && start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, token{start: start, text: text[start:]})
+	}
+
+	return tokens
+}
+
+// tokenise performs lexical analysis of text, which begins textOffset bytes into the text buffer,
+// and writes the results into the parse buffer
+func (zmachine *ZMachine) tokenise(text []zstring.ZSCII, textOffset int, parse memory.Address) error {
+	dictionary := GetDictionary(zmachine.Memory, zmachine.Memory.GetDictionaryAddress())
+	version := zmachine.Memory.GetVersion()
+
+	maxWords, nextAddress := zmachine.Memory.ReadByteNext(parse)
+	tokens := splitWords(text, dictionary.Separators)
+	tokens = tokens[:min(len(tokens), int(maxWords))]
+
+	nextAddress = zmachine.Memory.WriteByte(nextAddress, byte(len(tokens)))
+	for _, t := range tokens {
+		runes := []rune(zmachine.toUnicode(t.text))
+		encoded, err := zstring.EncodeWord(runes, zmachine.Charset, zmachine.Unicode, version)
+		if err != nil {
+			return fmt.Errorf("Error encoding input word: %w", err)
+		}
+
+		nextAddress = zmachine.Memory.WriteWord(nextAddress, word(dictionary.Lookup(encoded)))
+		nextAddress = zmachine.Memory.WriteByte(nextAddress, byte(len(t.text)))
+		nextAddress = zmachine.Memory.WriteByte(nextAddress, byte(t.start+textOffset))
+	}
+	return nil
+}
+
+// completeWord offers dictionary words that begin with the last word of text, returning the index
+// that word starts at along with the candidates. Only words Inform flags as verbs or nouns are
+// offered, so completion doesn't give away words the story uses for other purposes.
+func (zmachine *ZMachine) completeWord(text string) (int, []string) {
+	dictionary := GetDictionary(zmachine.Memory, zmachine.Memory.GetDictionaryAddress())
+
+	input := zmachine.toInputZSCII(text)
+	tokens := splitWords(input, dictionary.Separators)
+	if len(tokens) == 0 {
+		return len(input), nil
+	}
+
+	last := tokens[len(tokens)-1]
+	if last.start+len(last.text) != len(input) || slices.Contains(dictionary.Separators, last.text[0]) {
+		return len(input), nil
+	}
+
+	prefix := zmachine.toUnicode(last.text)
+	parser := zstring.NewParser(zmachine.Charset, zmachine.Unicode, zmachine.Memory.GetVersion(), zmachine.Memory.GetAbbreviation)
+
+	candidates := make([]string, 0)
+	for i := range dictionary.EntryCount {
+		if dictionary.Flags(i)&(DictFlag_Verb|DictFlag_Noun) == 0 {
+			continue
+		}
+
+		word, err := parser.Parse(dictionary.EncodedWord(i))
+		if err != nil {
+			continue
+		}
+
+		if len(word) > len(prefix) && strings.HasPrefix(word, prefix) {
+			candidates = append(candidates, word)
+		}
+	}
+	slices.Sort(candidates)
+
+	return last.start, slices.Compact(candidates)
+}
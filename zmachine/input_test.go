@@ -0,0 +1,46 @@
+package zmachine
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+)
+
+func TestSplitWords(t *testing.T) {
+	type spec struct {
+		input    string
+		expected []string
+		starts   []int
+	}
+
+	tests := map[string]spec{
+		"single word":       {input: "look", expected: []string{"look"}, starts: []int{0}},
+		"multiple spaces":   {input: "  take   lamp ", expected: []string{"take", "lamp"}, starts: []int{2, 9}},
+		"separator":         {input: "fred,go west", expected: []string{"fred", ",", "go", "west"}, starts: []int{0, 4, 5, 8}},
+		"leading separator": {input: ".look", expected: []string{".", "look"}, starts: []int{0, 1}},
+		"empty":             {input: "", expected: []string{}, starts: []int{}},
+	}
+
+	separators := []zstring.ZSCII{'.', ',', '"'}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			text := make([]zstring.ZSCII, 0, len(s.input))
+			for _, r := range s.input {
+				text = append(text, zstring.ZSCII(r))
+			}
+
+			tokens := splitWords(text, separators)
+			testassert.Same(t, len(s.expected), len(tokens))
+			for i, token := range tokens {
+				word := make([]rune, 0, len(token.text))
+				for _, zc := range token.text {
+					word = append(word, rune(zc))
+				}
+				testassert.Same(t, s.expected[i], string(word))
+				testassert.Same(t, s.starts[i], token.start)
+			}
+		})
+	}
+}
@@ -7,7 +7,6 @@
 	"slices"
 	"strings"
 
-	"github.com/Drakmyth/golang-zmachine/assert"
 	"github.com/Drakmyth/golang-zmachine/memory"
 )
 
@@ -70,17 +69,45 @@ type Instruction struct {
 	Text          string
 }
 
+// handlerNames caches the names of the handlers in the opcode tables, since finding a function's
+// name is slow
+var handlerNames = map[uintptr]string{}
+
+// Name is the opcode's name from the standard, taken from its handler function
+func (info InstructionInfo) Name() string {
+	if name, ok := handlerNames[reflect.ValueOf(info.Handler).Pointer()]; ok {
+		return name
+	}
+	return handlerName(info.Handler)
+}
+
+func handlerName(handler InstructionHandler) string {
+	function_path := strings.Split(runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(), ".")
+	return function_path[len(function_path)-1]
+}
+
 func (instruction Instruction) String() string {
-	function_path := strings.Split(runtime.FuncForPC(reflect.ValueOf(instruction.Handler).Pointer()).Name(), ".")
-	operation_name := strings.ToUpper(function_path[len(function_path)-1])
+	return instruction.describe(nil)
+}
+
+// describe formats the instruction, naming its operands from the symbols when there are any
+func (instruction Instruction) describe(symbols *symbolTable) string {
+	operation_name := strings.ToUpper(instruction.Name())
 
 	log_strings := make([]string, 0, len(instruction.Operands)+4)
 	log_strings = append(log_strings, fmt.Sprintf("%02x %s", instruction.Opcode, operation_name))
 	for i, operand := range instruction.Operands {
 		optype := instruction.OperandTypes[i]
+		if optype != OT_Variable {
+			if name := instruction.describeOperand(symbols, i, operand.asWord()); name != "" {
+				log_strings = append(log_strings, name)
+				continue
+			}
+		}
+
 		switch optype {
 		case OT_Variable:
-			log_strings = append(log_strings, fmt.Sprint(operand.asVarNum()))
+			log_strings = append(log_strings, instruction.variableName(symbols, operand.asVarNum()))
 		case OT_Small:
 			log_strings = append(log_strings, fmt.Sprintf("#%02x", operand.asByte()))
 		case OT_Large:
@@ -89,7 +116,7 @@ function_path := strings.Split(runtime.FuncForPC(reflect.ValueOf(instruction.Han
 	}
 
 	if instruction.StoresResult() {
-		log_strings = append(log_strings, fmt.Sprintf("-> %s", fmt.Sprint(instruction.StoreVariable)))
+		log_strings = append(log_strings, fmt.Sprintf("-> %s", instruction.variableName(symbols, instruction.StoreVariable.Number)))
 	}
 
 	if instruction.Branches() {
@@ -103,16 +130,17 @@ function_path := strings.Split(runtime.FuncForPC(reflect.ValueOf(instruction.Han
 	return strings.Join(log_strings, " ")
 }
 
-func (zmachine ZMachine) readInstruction(address memory.Address) (Instruction, memory.Address) {
+func (zmachine *ZMachine) readInstruction(address memory.Address) (Instruction, memory.Address, error) {
 	opcode, next_address := zmachine.readOpcode(address)
-	inst_info, ok := opcodes[opcode]
-	assert.True(ok, "unknown opcode: %02x", opcode)
+	inst_info, ok := lookupOpcode(zmachine.Memory.GetVersion(), opcode)
+	if !ok {
+		return Instruction{Opcode: opcode, Address: address}, next_address, fmt.Errorf("unknown opcode: %02x", opcode)
+	}
 
 	instruction := Instruction{InstructionInfo: inst_info, Opcode: opcode, Address: address}
-	assert.NotSame(instruction.Form, IF_Extended, "unimplemented: extended form instruction")
 
-	// Determine Variable Form operand types
-	if instruction.Form == IF_Variable {
+	// Determine Variable and Extended Form operand types
+	if instruction.Form == IF_Variable || instruction.Form == IF_Extended {
 		var types_byte uint8
 		types_byte, next_address = zmachine.Memory.ReadByteNext(next_address)
 
@@ -146,12 +174,17 @@ func (zmachine ZMachine) readInstruction(address memory.Address) (Instruction, m
 		instruction.Branch = branch
 	}
 
-	// if instruction.HasText() {
-	//     // TODO: Implement text handling
-	// }
-
 	instruction.NextAddress = next_address
-	return instruction, next_address
+	return instruction, next_address, nil
+}
+
+// end is the address after the instruction. Text printed by the instruction follows its operands,
+// so it isn't counted in NextAddress.
+func (instruction Instruction) end(m *memory.Memory) memory.Address {
+	if !instruction.HasText() {
+		return instruction.NextAddress
+	}
+	return instruction.NextAddress.OffsetBytes(m.GetZString(instruction.NextAddress).LenBytes())
 }
 
 type BranchBehavior uint8
@@ -205,10 +238,6 @@ func (operand Operand) asWord() word {
 	return word(operand)
 }
 
-func (operand Operand) asBytes() []byte {
-	return []byte{byte(operand >> 8), byte(operand)}
-}
-
 func (operand Operand) asByte() byte {
 	return byte(operand)
 }
@@ -0,0 +1,208 @@
+package zmachine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const metaCommandPrefix = "/"
+
+// A metaCommand is handled by the interpreter rather than the story. It reports whether it
+// restored a saved state, in which case the instruction that read it must not continue.
+type metaCommand struct {
+	usage       string
+	description string
+	run         func(zmachine *ZMachine, args []string) (bool, error)
+}
+
+var metaCommands map[string]metaCommand
+
+func init() {
+	metaCommands = map[string]metaCommand{
+		"save":       {"/save [file]", "Save the game in Quetzal format", metaSave},
+		"restore":    {"/restore [file]", "Restore a game saved in Quetzal format", metaRestore},
+		"undo":       {"/undo", "Take back the last command", metaUndo},
+		"transcript": {"/transcript on [file]|off", "Record the game to a text file", metaTranscript},
+		"script":     {"/script <file>", "Replay commands from a file, one per line", metaScript},
+		"seed":       {"/seed <n>", "Seed the random number generator", metaSeed},
+		"quit":       {"/quit", "Quit without asking the game", metaQuit},
+		"help":       {"/help", "List these commands", metaHelp},
+	}
+}
+
+func isMetaCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), metaCommandPrefix)
+}
+
+// runMetaCommand carries out a line of input starting with the meta-command prefix. Problems
+// are reported to the player instead of stopping the game.
+func (zmachine *ZMachine) runMetaCommand(input string) bool {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(input), metaCommandPrefix))
+	if len(fields) == 0 {
+		fields = []string{"help"}
+	}
+
+	command, ok := metaCommands[strings.ToLower(fields[0])]
+	if !ok {
+		zmachine.printMeta(fmt.Sprintf("Unknown command %q, try /help", fields[0]))
+		return false
+	}
+
+	restored, err := command.run(zmachine, fields[1:])
+	if err != nil {
+		zmachine.printMeta(err.Error())
+	}
+	return restored
+}
+
+func (zmachine *ZMachine) printMeta(message string) {
+	zmachine.Screen.PrintText("[" + message + "]\n")
+}
+
+// storyFile names a file alongside the story, used when the player doesn't give one
+func (zmachine *ZMachine) storyFile(args []string, extension string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+
+	path := zmachine.Memory.GetPath()
+	return strings.TrimSuffix(path, filepath.Ext(path)) + extension
+}
+
+func metaSave(zmachine *ZMachine, args []string) (bool, error) {
+	path := zmachine.storyFile(args, ".qzl")
+	err := zmachine.saveFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	zmachine.printMeta("Saved to " + path)
+	return false, nil
+}
+
+func metaRestore(zmachine *ZMachine, args []string) (bool, error) {
+	path := zmachine.storyFile(args, ".qzl")
+	err := zmachine.restoreFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	zmachine.undo, zmachine.prompt = nil, nil
+	zmachine.printMeta("Restored from " + path)
+	return true, nil
+}
+
+func metaUndo(zmachine *ZMachine, args []string) (bool, error) {
+	if zmachine.undo == nil {
+		return false, errors.New("Nothing to undo")
+	}
+
+	err := zmachine.restoreSnapshot(*zmachine.undo)
+	if err != nil {
+		return false, err
+	}
+
+	// Only one command can be taken back
+	zmachine.undo, zmachine.prompt = nil, nil
+	zmachine.printMeta("Undone")
+	return true, nil
+}
+
+func metaTranscript(zmachine *ZMachine, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, errors.New("Usage: " + metaCommands["transcript"].usage)
+	}
+
+	if zmachine.transcript != nil {
+		zmachine.Screen.Transcript = nil
+		zmachine.transcript.Close()
+		zmachine.transcript = nil
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "off":
+		zmachine.printMeta("Transcript off")
+		return false, nil
+	case "on":
+		path := zmachine.storyFile(args[1:], ".txt")
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return false, err
+		}
+
+		zmachine.transcript = file
+		zmachine.Screen.Transcript = file
+		zmachine.printMeta("Transcript on, writing to " + path)
+		return false, nil
+	}
+
+	return false, errors.New("Usage: " + metaCommands["transcript"].usage)
+}
+
+func metaScript(zmachine *ZMachine, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, errors.New("Usage: " + metaCommands["script"].usage)
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+
+	// A script run from another script replays before the rest of the outer one
+	zmachine.script = append(lines, zmachine.script...)
+	return false, nil
+}
+
+func metaSeed(zmachine *ZMachine, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, errors.New("Usage: " + metaCommands["seed"].usage)
+	}
+
+	seed, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return false, errors.New("Seed must be a whole number")
+	}
+
+	zmachine.Random.Seed(seed)
+	zmachine.printMeta(fmt.Sprintf("Random seed set to %d", seed))
+	return false, nil
+}
+
+func metaQuit(zmachine *ZMachine, args []string) (bool, error) {
+	zmachine.Shutdown(0)
+	return false, nil
+}
+
+func metaHelp(zmachine *ZMachine, args []string) (bool, error) {
+	names := make([]string, 0, len(metaCommands))
+	for name := range metaCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	builder := strings.Builder{}
+	builder.WriteString("Interpreter commands:\n")
+	for _, name := range names {
+		command := metaCommands[name]
+		builder.WriteString(fmt.Sprintf("  %-26s %s\n", command.usage, command.description))
+	}
+	zmachine.Screen.PrintText(builder.String())
+	return false, nil
+}
@@ -1,7 +1,8 @@
 package zmachine
 
 import (
-	"github.com/Drakmyth/golang-zmachine/assert"
+	"fmt"
+
 	"github.com/Drakmyth/golang-zmachine/memory"
 	"github.com/Drakmyth/golang-zmachine/zstring"
 )
@@ -40,37 +41,31 @@
 	_
 )
 
-type object struct {
+// Object is an entry in the object table. Get one from ObjectTable.Get, which checks the object
+// exists.
+type Object struct {
+	Id      ObjectId
 	mem     *memory.Memory
 	address memory.Address
+	symbols *symbolTable
 }
 
-func GetObject(mem *memory.Memory, oid ObjectId) *object {
-	objectTableAddr := mem.GetObjectsAddress()
-
-	propCount := 31
-	objectSize := 9
-	if mem.GetVersion() > 3 {
-		propCount = 63
-		objectSize = 14
+// checkAttribute reports an error for attribute numbers the version doesn't have
+func (o Object) checkAttribute(index int) error {
+	maxAttributes := 32
+	if o.mem.GetVersion() > 3 {
+		maxAttributes = 48
 	}
-
-	objectTreeAddr := objectTableAddr.OffsetWords(propCount)
-
-	objectAddr := objectTreeAddr.OffsetBytes(objectSize * int(oid-1)) // Object IDs start at 1
-
-	return &object{
-		mem:     mem,
-		address: objectAddr,
+	if index < 0 || index >= maxAttributes {
+		return fmt.Errorf("Attribute %d of %s is out of range, objects have %d attributes", index, describeObject(o.symbols, o.Id), maxAttributes)
 	}
+	return nil
 }
 
-func (o object) HasAttribute(index int) bool {
-	maxAttributes := 32
-	if o.mem.GetVersion() > 3 {
-		maxAttributes = 48
+func (o Object) HasAttribute(index int) (bool, error) {
+	if err := o.checkAttribute(index); err != nil {
+		return false, err
 	}
-	assert.LessThan(maxAttributes, index, "Invalid attribute index")
 
 	bytesToSkip := index / 8
 	newIndex := index % 8
@@ -78,15 +73,13 @@ func (o object) HasAttribute(index int) bool {
 	attributeByteAddr := o.address.OffsetBytes(idx_Attributes).OffsetBytes(bytesToSkip)
 	attributeByte := o.mem.ReadByte(attributeByteAddr)
 	hasAttribute := (attributeByte >> (7 - newIndex)) & 0b1
-	return hasAttribute == 1
+	return hasAttribute == 1, nil
 }
 
-func (o *object) ClearAttribute(index int) {
-	maxAttributes := 32
-	if o.mem.GetVersion() > 3 {
-		maxAttributes = 48
+func (o *Object) ClearAttribute(index int) error {
+	if err := o.checkAttribute(index); err != nil {
+		return err
 	}
-	assert.LessThan(maxAttributes, index, "Invalid attribute index")
 
 	bytesToSkip := index / 8
 	newIndex := index % 8
@@ -96,14 +89,13 @@ func (o *object) ClearAttribute(index int) {
 	mask := ^byte(0b1 << (7 - newIndex))
 	attributeByte = attributeByte & mask
 	o.mem.WriteByte(attributeByteAddr, attributeByte)
+	return nil
 }
 
-func (o *object) SetAttribute(index int) {
-	maxAttributes := 32
-	if o.mem.GetVersion() > 3 {
-		maxAttributes = 48
+func (o *Object) SetAttribute(index int) error {
+	if err := o.checkAttribute(index); err != nil {
+		return err
 	}
-	assert.LessThan(maxAttributes, index, "Invalid attribute index")
 
 	bytesToSkip := index / 8
 	newIndex := index % 8
@@ -112,9 +104,10 @@ func (o *object) SetAttribute(index int) {
 	attributeByte := o.mem.ReadByte(attributeByteAddr)
 	attributeByte = attributeByte | (0b1 << (7 - newIndex))
 	o.mem.WriteByte(attributeByteAddr, attributeByte)
+	return nil
 }
 
-func (o object) Parent() ObjectId {
+func (o Object) Parent() ObjectId {
 	if o.mem.GetVersion() <= 3 {
 		return ObjectId(o.mem.ReadByte(o.address.OffsetBytes(idxV1_Parent)))
 	} else {
@@ -122,7 +115,7 @@ func (o object) Parent() ObjectId {
 	}
 }
 
-func (o object) Sibling() ObjectId {
+func (o Object) Sibling() ObjectId {
 	if o.mem.GetVersion() <= 3 {
 		return ObjectId(o.mem.ReadByte(o.address.OffsetBytes(idxV1_Sibling)))
 	} else {
@@ -130,7 +123,7 @@ func (o object) Sibling() ObjectId {
 	}
 }
 
-func (o object) Child() ObjectId {
+func (o Object) Child() ObjectId {
 	if o.mem.GetVersion() <= 3 {
 		return ObjectId(o.mem.ReadByte(o.address.OffsetBytes(idxV1_Child)))
 	} else {
@@ -138,7 +131,7 @@ func (o object) Child() ObjectId {
 	}
 }
 
-func (o *object) SetParent(parent ObjectId) {
+func (o *Object) SetParent(parent ObjectId) {
 	if o.mem.GetVersion() <= 3 {
 		o.mem.WriteByte(o.address.OffsetBytes(idxV1_Parent), byte(parent))
 	} else {
@@ -146,7 +139,7 @@ func (o *object) SetParent(parent ObjectId) {
 	}
 }
 
-func (o *object) SetSibling(sibling ObjectId) {
+func (o *Object) SetSibling(sibling ObjectId) {
 	if o.mem.GetVersion() <= 3 {
 		o.mem.WriteByte(o.address.OffsetBytes(idxV1_Sibling), byte(sibling))
 	} else {
@@ -154,7 +147,7 @@ func (o *object) SetSibling(sibling ObjectId) {
 	}
 }
 
-func (o *object) SetChild(child ObjectId) {
+func (o *Object) SetChild(child ObjectId) {
 	if o.mem.GetVersion() <= 3 {
 		o.mem.WriteByte(o.address.OffsetBytes(idxV1_Child), byte(child))
 	} else {
@@ -162,71 +155,114 @@ func (o *object) SetChild(child ObjectId) {
 	}
 }
 
-func (o *object) ShortName() zstring.ZString {
+func (o Object) propertyTableAddress() memory.Address {
 	propertyTableOffset := idxV1_PropertiesAddr
 	if o.mem.GetVersion() > 3 {
 		propertyTableOffset = idxV4_PropertiesAddr
 	}
 
 	propertyTablePointer := o.address.OffsetBytes(propertyTableOffset)
-	propertyTableAddr := memory.Address(o.mem.ReadWord(propertyTablePointer))
-	return o.mem.GetZString(propertyTableAddr.OffsetBytes(1))
+	return memory.Address(o.mem.ReadWord(propertyTablePointer))
+}
+
+func (o *Object) ShortName() zstring.ZString {
+	return o.mem.GetZString(o.propertyTableAddress().OffsetBytes(1))
 }
 
-func (o object) Property(pid PropertyId) []byte {
-	o.assertValidPropertyId(pid)
+func (o Object) Property(pid PropertyId) ([]byte, error) {
+	if err := o.checkPropertyId(pid); err != nil {
+		return nil, err
+	}
 	data, found := o.findProperty(pid)
 
 	if found {
-		return data
+		return data, nil
 	}
 
-	return getPropertyDefault(o.mem, pid)
+	return getPropertyDefault(o.mem, pid), nil
 }
 
-func (o *object) SetProperty(pid PropertyId, data []byte) {
-	o.assertValidPropertyId(pid)
-	existingData, found := o.findProperty(pid)
-	assert.True(found, "Cannot set property that does not exist")
+// PropertyValue reads a property as a value: a 1-byte property is that byte and a 2-byte property
+// is a word. Reading a longer property this way is illegal, so it's an error, though its first
+// word is still returned as other interpreters would read it.
+func (o Object) PropertyValue(pid PropertyId) (word, error) {
+	data, err := o.Property(pid)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 1 {
+		return word(data[0]), nil
+	}
+
+	value := word(data[0])<<8 | word(data[1])
+	if len(data) > 2 {
+		return value, fmt.Errorf("%s of %s is %d bytes long, only 1 and 2 byte properties can be read as a value",
+			describeProperty(o.symbols, pid), describeObject(o.symbols, o.Id), len(data))
+	}
+	return value, nil
+}
+
+// SetProperty writes a value to a property the object has. A 1-byte property gets the low byte
+// of the value, and longer properties can't be written as a value at all.
+func (o *Object) SetProperty(pid PropertyId, value word) error {
+	if err := o.checkPropertyId(pid); err != nil {
+		return err
+	}
+	data, found := o.findProperty(pid)
+	if !found {
+		return fmt.Errorf("%s has no %s to set", describeObject(o.symbols, o.Id), describeProperty(o.symbols, pid))
+	}
 
-	for i := 0; i < min(len(data), len(existingData)); i++ {
-		// TODO: Is this right if the arrays are different lengths?
-		existingData[i] = data[i]
+	switch len(data) {
+	case 1:
+		data[0] = byte(value)
+	case 2:
+		data[0], data[1] = byte(value>>8), byte(value)
+	default:
+		return fmt.Errorf("%s of %s is %d bytes long, only 1 and 2 byte properties can be set", describeProperty(o.symbols, pid),
+			describeObject(o.symbols, o.Id), len(data))
 	}
+	return nil
 }
 
-func (o object) GetNextPropertyId(pid PropertyId) PropertyId {
-	o.assertValidPropertyId(pid)
+func (o Object) GetNextPropertyId(pid PropertyId) (PropertyId, error) {
+	if err := o.checkPropertyId(pid); err != nil {
+		return 0, err
+	}
 	propId, _, nextAddress := o.getFirstProperty()
 
 	if pid == 0 {
-		return propId
+		return propId, nil
 	}
 
 	for propId != pid && propId != 0 {
 		propId, _, nextAddress = parseProperty(o.mem, nextAddress)
 	}
-	assert.NotSame(propId, 0, "Can't get next property of non-existant property")
+	if propId == 0 {
+		return 0, fmt.Errorf("%s has no %s to follow", describeObject(o.symbols, o.Id), describeProperty(o.symbols, pid))
+	}
 
 	propId, _, _ = parseProperty(o.mem, nextAddress)
-	return propId
+	return propId, nil
 }
 
-func (o object) GetPropertyDataAddress(pid PropertyId) memory.Address {
-	o.assertValidPropertyId(pid)
+func (o Object) GetPropertyDataAddress(pid PropertyId) (memory.Address, error) {
+	if err := o.checkPropertyId(pid); err != nil {
+		return 0, err
+	}
 	propId, data, nextAddress := o.getFirstProperty()
 
 	for propId != pid && propId != 0 {
 		propId, data, nextAddress = parseProperty(o.mem, nextAddress)
 	}
 	if propId == 0 {
-		return 0
+		return 0, nil
 	}
 
-	return nextAddress.OffsetBytes(-len(data))
+	return nextAddress.OffsetBytes(-len(data)), nil
 }
 
-func (o object) findProperty(pid PropertyId) ([]byte, bool) {
+func (o Object) findProperty(pid PropertyId) ([]byte, bool) {
 	propId, data, nextAddress := o.getFirstProperty()
 
 	for propId != pid && propId != 0 {
@@ -240,15 +276,8 @@ func (o object) findProperty(pid PropertyId) ([]byte, bool) {
 	return []byte{}, false
 }
 
-func (o object) getFirstProperty() (PropertyId, []byte, memory.Address) {
-	propertyTableOffset := idxV1_PropertiesAddr
-	if o.mem.GetVersion() > 3 {
-		propertyTableOffset = idxV4_PropertiesAddr
-	}
-
-	propertyTablePointer := o.address.OffsetBytes(propertyTableOffset)
-	propertyTableAddr := memory.Address(o.mem.ReadWord(propertyTablePointer))
-	headerLength, headerDataAddr := o.mem.ReadByteNext(propertyTableAddr)
+func (o Object) getFirstProperty() (PropertyId, []byte, memory.Address) {
+	headerLength, headerDataAddr := o.mem.ReadByteNext(o.propertyTableAddress())
 	propertyAddr := headerDataAddr.OffsetWords(int(headerLength))
 
 	return parseProperty(o.mem, propertyAddr)
@@ -304,11 +333,15 @@ func getPropertyDefault(mem *memory.Memory, pid PropertyId) []byte {
 	return mem.GetBytes(propDefaultAddr, 2)
 }
 
-func (o object) assertValidPropertyId(pid PropertyId) {
+// checkPropertyId reports an error for property numbers the version doesn't have
+func (o Object) checkPropertyId(pid PropertyId) error {
 	maxPropertyId := 31
 	if o.mem.GetVersion() > 3 {
 		maxPropertyId = 63
 	}
-
-	assert.LessThanEqual(maxPropertyId, int(pid), "PropertyId too large for version")
+	if int(pid) > maxPropertyId {
+		return fmt.Errorf("%s of %s is out of range, objects have %d properties", describeProperty(o.symbols, pid),
+			describeObject(o.symbols, o.Id), maxPropertyId)
+	}
+	return nil
 }
@@ -0,0 +1,141 @@
+package zmachine
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+)
+
+// newPropertyTestMachine holds testObjects with the box's long property 20 replaced, and a
+// default of 0x0107 for property 7
+func newPropertyTestMachine(t *testing.T, version int, long []byte) *ZMachine {
+	t.Helper()
+
+	objects := slices.Clone(testObjects)
+	objects[0].Properties = []teststory.Property{
+		{Number: 5, Data: []byte{0x56}},
+		{Number: 10, Data: []byte{0x12, 0x34}},
+		{Number: 20, Data: long},
+	}
+	return newObjectTestMachine(t, teststory.Story{Version: version, Objects: objects, Defaults: map[byte]word{7: 0x0107}})
+}
+
+func propertyValue(t *testing.T, object *Object, pid PropertyId) word {
+	t.Helper()
+
+	value, err := object.PropertyValue(pid)
+	testassert.NoError(t, err)
+	return value
+}
+
+func TestObject_Properties(t *testing.T) {
+	versions := map[string]struct {
+		version int
+		long    []byte
+	}{
+		"V3":         {3, []byte{1, 2, 3}},
+		"V5":         {5, []byte{1, 2, 3}},
+		"V5 64 byte": {5, make([]byte, 64)},
+	}
+
+	for name, s := range versions {
+		t.Run(name, func(t *testing.T) {
+			zmachine := newPropertyTestMachine(t, s.version, s.long)
+			object, err := zmachine.Objects.Get(1)
+			testassert.NoError(t, err)
+
+			testassert.Same(t, 0x56, propertyValue(t, object, 5))
+			testassert.Same(t, 0x1234, propertyValue(t, object, 10))
+			value, err := object.PropertyValue(20)
+			testassert.Same(t, word(s.long[0])<<8|word(s.long[1]), value)
+			testassert.ErrorMessage(t, fmt.Sprintf("Property 20 of Object 1 is %d bytes long, only 1 and 2 byte properties can be read as a value",
+				len(s.long)), err)
+			data, err := object.Property(20)
+			testassert.NoError(t, err)
+			testassert.Same(t, len(s.long), len(data))
+
+			// Missing properties read their default
+			testassert.Same(t, 0x0107, propertyValue(t, object, 7))
+
+			testassert.NoError(t, object.SetProperty(5, 0xabcd))
+			testassert.Same(t, 0xcd, propertyValue(t, object, 5))
+			testassert.Same(t, 0x1234, propertyValue(t, object, 10)) // Neighbours are untouched
+
+			testassert.NoError(t, object.SetProperty(10, 0xabcd))
+			testassert.Same(t, 0xabcd, propertyValue(t, object, 10))
+			testassert.Same(t, 0xcd, propertyValue(t, object, 5))
+
+			err = object.SetProperty(20, 1)
+			testassert.ErrorMessage(t, fmt.Sprintf("Property 20 of Object 1 is %d bytes long, only 1 and 2 byte properties can be set",
+				len(s.long)), err)
+
+			err = object.SetProperty(7, 1)
+			testassert.ErrorMessage(t, "Object 1 has no Property 7 to set", err)
+		})
+	}
+}
+
+func nextPropertyId(t *testing.T, object *Object, pid PropertyId) PropertyId {
+	t.Helper()
+
+	next, err := object.GetNextPropertyId(pid)
+	testassert.NoError(t, err)
+	return next
+}
+
+func TestObject_GetNextPropertyId(t *testing.T) {
+	for _, version := range []int{3, 5} {
+		zmachine := newPropertyTestMachine(t, version, []byte{1, 2, 3})
+		object, _ := zmachine.Objects.Get(1)
+
+		testassert.Same(t, PropertyId(20), nextPropertyId(t, object, 0))
+		testassert.Same(t, PropertyId(10), nextPropertyId(t, object, 20))
+		testassert.Same(t, PropertyId(5), nextPropertyId(t, object, 10))
+		testassert.Same(t, PropertyId(0), nextPropertyId(t, object, 5))
+
+		_, err := object.GetNextPropertyId(7)
+		testassert.ErrorMessage(t, "Object 1 has no Property 7 to follow", err)
+
+		empty, _ := zmachine.Objects.Get(2)
+		testassert.Same(t, PropertyId(0), nextPropertyId(t, empty, 0))
+	}
+}
+
+func TestPropertyHandlers(t *testing.T) {
+	for _, version := range []int{3, 5} {
+		zmachine := newPropertyTestMachine(t, version, []byte{1, 2, 3})
+		store := zmachine.getVariable(0)
+
+		_, err := put_prop(zmachine, Instruction{Operands: []Operand{1, 5, 0x1278}})
+		testassert.NoError(t, err)
+		_, err = get_prop(zmachine, Instruction{Operands: []Operand{1, 5}, StoreVariable: store})
+		testassert.NoError(t, err)
+		value, err := store.Read()
+		testassert.NoError(t, err)
+		testassert.Same(t, 0x78, value)
+
+		_, err = get_prop(zmachine, Instruction{Operands: []Operand{1, 10}, StoreVariable: store})
+		testassert.NoError(t, err)
+		value, err = store.Read()
+		testassert.NoError(t, err)
+		testassert.Same(t, 0x1234, value)
+
+		_, err = get_prop_addr(zmachine, Instruction{Operands: []Operand{1, 10}, StoreVariable: store})
+		testassert.NoError(t, err)
+		address, err := store.Read()
+		testassert.NoError(t, err)
+		_, err = get_prop_len(zmachine, Instruction{Operands: []Operand{Operand(address)}, StoreVariable: store})
+		testassert.NoError(t, err)
+		value, err = store.Read()
+		testassert.NoError(t, err)
+		testassert.Same(t, 2, value)
+
+		_, err = put_prop(zmachine, Instruction{Operands: []Operand{1, 20, 1}})
+		testassert.ErrorMessage(t, "Property 20 of Object 1 is 3 bytes long, only 1 and 2 byte properties can be set", err)
+		_, err = put_prop(zmachine, Instruction{Operands: []Operand{2, 5, 1}})
+		testassert.ErrorMessage(t, "Object 2 has no Property 5 to set", err)
+	}
+}
@@ -0,0 +1,195 @@
+package zmachine
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+// ObjectError reports an object number that doesn't name an object in the table
+type ObjectError struct {
+	Object  ObjectId
+	Count   int // Objects in the table
+	symbols *symbolTable
+}
+
+func (e *ObjectError) Error() string {
+	if e.Object == 0 {
+		return "Object 0 is not an object"
+	}
+	return fmt.Sprintf("%s is out of range, the story has %d objects", describeObject(e.symbols, e.Object), e.Count)
+}
+
+// ObjectTable is the story's tree of objects. Every object number is checked before its entry
+// is read, so a bad number is an error instead of a read from whatever memory it lands on.
+type ObjectTable struct {
+	mem       *memory.Memory
+	entries   memory.Address // Address of object 1
+	entrySize int
+	count     int
+	symbols   *symbolTable // Names objects in errors, once debug information is loaded
+}
+
+// NewObjectTable finds the objects in a story. The header doesn't say how many there are, but
+// compilers put the property tables straight after the entries, so the entries end where the
+// first object's property table begins.
+func NewObjectTable(mem *memory.Memory) *ObjectTable {
+	defaults, entrySize, maxObjects := 31, 9, 255
+	if mem.GetVersion() > 3 {
+		defaults, entrySize, maxObjects = 63, 14, 0xffff
+	}
+
+	table := &ObjectTable{
+		mem:       mem,
+		entries:   mem.GetObjectsAddress().OffsetWords(defaults),
+		entrySize: entrySize,
+	}
+
+	first := table.entry(1)
+	if int(first)+entrySize > mem.Size() {
+		return table
+	}
+
+	properties := int(Object{mem: mem, address: first}.propertyTableAddress())
+	if properties > int(table.entries) {
+		table.count = min((properties-int(table.entries))/entrySize, maxObjects)
+	}
+	return table
+}
+
+func (table *ObjectTable) entry(oid ObjectId) memory.Address {
+	return table.entries.OffsetBytes(table.entrySize * (int(oid) - 1)) // Object IDs start at 1
+}
+
+// Count is the number of objects in the table
+func (table *ObjectTable) Count() int {
+	return table.count
+}
+
+// Get returns an object, or an *ObjectError if there's no such object
+func (table *ObjectTable) Get(oid ObjectId) (*Object, error) {
+	if oid == 0 || int(oid) > table.count {
+		return nil, &ObjectError{Object: oid, Count: table.count, symbols: table.symbols}
+	}
+
+	return &Object{Id: oid, mem: table.mem, address: table.entry(oid), symbols: table.symbols}, nil
+}
+
+// Children lists the children of an object, from its first child along the chain of siblings
+func (table *ObjectTable) Children(oid ObjectId) ([]ObjectId, error) {
+	object, err := table.Get(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]ObjectId, 0)
+	for child := object.Child(); child != 0; {
+		// A chain longer than the table must loop back on itself
+		if len(children) == table.count {
+			return nil, fmt.Errorf("Children of %s loop back on themselves", describeObject(table.symbols, oid))
+		}
+		children = append(children, child)
+
+		sibling, err := table.Get(child)
+		if err != nil {
+			return nil, err
+		}
+		child = sibling.Sibling()
+	}
+	return children, nil
+}
+
+// Iterate visits every object in the table in order
+func (table *ObjectTable) Iterate() iter.Seq[*Object] {
+	return func(yield func(*Object) bool) {
+		for i := range table.count {
+			object, _ := table.Get(ObjectId(i + 1))
+			if !yield(object) {
+				return
+			}
+		}
+	}
+}
+
+// Remove detaches an object from its parent, taking its children with it
+func (table *ObjectTable) Remove(oid ObjectId) error {
+	object, err := table.Get(oid)
+	if err != nil {
+		return err
+	}
+
+	if object.Parent() == 0 {
+		return nil
+	}
+
+	parent, err := table.Get(object.Parent())
+	if err != nil {
+		return err
+	}
+
+	if parent.Child() == oid {
+		parent.SetChild(object.Sibling())
+	} else {
+		previous, err := table.previousSibling(parent, oid)
+		if err != nil {
+			return err
+		}
+		previous.SetSibling(object.Sibling())
+	}
+
+	object.SetParent(0)
+	object.SetSibling(0)
+	return nil
+}
+
+// previousSibling finds the child of parent whose next sibling is oid
+func (table *ObjectTable) previousSibling(parent *Object, oid ObjectId) (*Object, error) {
+	children, err := table.Children(parent.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(children); i++ {
+		if children[i] == oid {
+			return table.Get(children[i-1])
+		}
+	}
+	return nil, fmt.Errorf("%s is not among the children of its parent %s", describeObject(table.symbols, oid), describeObject(table.symbols, parent.Id))
+}
+
+// Move makes an object the first child of destination, removing it from its old parent first
+func (table *ObjectTable) Move(oid ObjectId, destination ObjectId) error {
+	object, err := table.Get(oid)
+	if err != nil {
+		return err
+	}
+	parent, err := table.Get(destination)
+	if err != nil {
+		return err
+	}
+
+	// Moving an object inside itself would cut it off from the tree
+	for ancestor, depth := parent, 0; ; depth++ {
+		if ancestor.Id == oid {
+			return fmt.Errorf("Cannot move %s inside itself", describeObject(table.symbols, oid))
+		}
+		if ancestor.Parent() == 0 || depth == table.count {
+			break
+		}
+		ancestor, err = table.Get(ancestor.Parent())
+		if err != nil {
+			return err
+		}
+	}
+
+	err = table.Remove(oid)
+	if err != nil {
+		return err
+	}
+
+	object.SetParent(destination)
+	object.SetSibling(parent.Child())
+	parent.SetChild(oid)
+	return nil
+}
@@ -0,0 +1,130 @@
+package zmachine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+)
+
+// newObjectTestMachine loads a story without running it, for testing its objects
+func newObjectTestMachine(t *testing.T, story teststory.Story) *ZMachine {
+	t.Helper()
+
+	m, err := story.Memory()
+	testassert.NoError(t, err)
+	zmachine := &ZMachine{Memory: m, Stack: NewCallStack(), Objects: NewObjectTable(m)}
+	zmachine.Stack.Push(Frame{}, 0)
+	return zmachine
+}
+
+func TestNewObjectTable_CountsObjects(t *testing.T) {
+	for _, version := range []int{3, 5} {
+		zmachine := newObjectTestMachine(t, teststory.Story{Version: version, Objects: testObjects})
+		testassert.Same(t, 4, zmachine.Objects.Count())
+
+		count := 0
+		for object := range zmachine.Objects.Iterate() {
+			count++
+			testassert.Same(t, ObjectId(count), object.Id)
+		}
+		testassert.Same(t, 4, count)
+	}
+}
+
+func TestObjectTable_Get(t *testing.T) {
+	zmachine := newObjectTestMachine(t, teststory.Story{Objects: testObjects})
+
+	object, err := zmachine.Objects.Get(2)
+	testassert.NoError(t, err)
+	testassert.Same(t, ObjectId(1), object.Parent())
+	testassert.Same(t, ObjectId(3), object.Sibling())
+
+	_, err = zmachine.Objects.Get(0)
+	testassert.ErrorMessage(t, "Object 0 is not an object", err)
+
+	_, err = zmachine.Objects.Get(5)
+	zerr := &ObjectError{}
+	testassert.True(t, errors.As(err, &zerr))
+	testassert.Same(t, ObjectId(5), zerr.Object)
+	testassert.ErrorMessage(t, "Object 5 is out of range, the story has 4 objects", err)
+}
+
+func TestObjectTable_Remove(t *testing.T) {
+	for _, version := range []int{3, 5} {
+		zmachine := newObjectTestMachine(t, teststory.Story{Version: version, Objects: testObjects})
+
+		testassert.NoError(t, zmachine.Objects.Remove(3))
+		children, err := zmachine.Objects.Children(1)
+		testassert.NoError(t, err)
+		testassert.True(t, slices.Equal([]ObjectId{2}, children))
+
+		testassert.NoError(t, zmachine.Objects.Remove(2))
+		children, err = zmachine.Objects.Children(1)
+		testassert.NoError(t, err)
+		testassert.Same(t, 0, len(children))
+
+		object, _ := zmachine.Objects.Get(2)
+		testassert.Same(t, ObjectId(0), object.Parent())
+		testassert.Same(t, ObjectId(0), object.Sibling())
+
+		// Removing an object without a parent does nothing
+		testassert.NoError(t, zmachine.Objects.Remove(4))
+	}
+}
+
+func TestObjectTable_Move(t *testing.T) {
+	for _, version := range []int{3, 5} {
+		zmachine := newObjectTestMachine(t, teststory.Story{Version: version, Objects: testObjects})
+
+		testassert.NoError(t, zmachine.Objects.Move(4, 1))
+		children, err := zmachine.Objects.Children(1)
+		testassert.NoError(t, err)
+		testassert.True(t, slices.Equal([]ObjectId{4, 2, 3}, children))
+
+		testassert.NoError(t, zmachine.Objects.Move(3, 2))
+		children, _ = zmachine.Objects.Children(1)
+		testassert.True(t, slices.Equal([]ObjectId{4, 2}, children))
+		children, _ = zmachine.Objects.Children(2)
+		testassert.True(t, slices.Equal([]ObjectId{3}, children))
+
+		err = zmachine.Objects.Move(1, 3)
+		testassert.ErrorMessage(t, "Cannot move Object 1 inside itself", err)
+		children, _ = zmachine.Objects.Children(1)
+		testassert.True(t, slices.Equal([]ObjectId{4, 2}, children))
+	}
+}
+
+func TestObjectTable_ChildrenLoop(t *testing.T) {
+	zmachine := newObjectTestMachine(t, teststory.Story{Objects: []teststory.Object{{Child: 2}, {Parent: 1, Sibling: 3}, {Parent: 1, Sibling: 2}}})
+	_, err := zmachine.Objects.Children(1)
+	testassert.ErrorMessage(t, "Children of Object 1 loop back on themselves", err)
+}
+
+func TestObjectHandlers_RejectObjectZero(t *testing.T) {
+	handlers := map[string]struct {
+		handler  func(*ZMachine, Instruction) (bool, error)
+		operands []Operand
+	}{
+		"insert_obj object":      {insert_obj, []Operand{0, 1}},
+		"insert_obj destination": {insert_obj, []Operand{2, 0}},
+		"remove_obj":             {remove_obj, []Operand{0}},
+		"jin":                    {jin, []Operand{0, 1}},
+	}
+
+	for name, s := range handlers {
+		t.Run(name, func(t *testing.T) {
+			zmachine := newObjectTestMachine(t, teststory.Story{Objects: testObjects})
+			before := zmachine.Memory.GetDynamicMemory()
+
+			_, err := s.handler(zmachine, Instruction{Operands: s.operands})
+
+			zerr := &ObjectError{}
+			testassert.True(t, errors.As(err, &zerr))
+			testassert.Same(t, ObjectId(0), zerr.Object)
+			testassert.True(t, slices.Equal(before, zmachine.Memory.GetDynamicMemory()))
+		})
+	}
+}
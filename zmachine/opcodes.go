@@ -2,13 +2,11 @@
 
 import (
 	"fmt"
-	"math/rand/v2"
+	"reflect"
 	"slices"
-	"strings"
-	"time"
 
-	"github.com/Drakmyth/golang-zmachine/assert"
 	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/screen"
 	"github.com/Drakmyth/golang-zmachine/zstring"
 )
 
@@ -158,8 +156,8 @@
 	0xaf: {IF_Short, IM_Store, []OperandType{OT_Variable}, not}, // This opcode changed to `call_1n` in V5
 	0xb0: {IF_Short, IM_None, []OperandType{}, rtrue},
 	0xb1: {IF_Short, IM_None, []OperandType{}, rfalse},
-	0xb2: {IF_Short, IM_None, []OperandType{}, print},
-	0xb3: {IF_Short, IM_None, []OperandType{}, print_ret},
+	0xb2: {IF_Short, IM_Text, []OperandType{}, print},
+	0xb3: {IF_Short, IM_Text, []OperandType{}, print_ret},
 	0xb8: {IF_Short, IM_None, []OperandType{}, ret_popped},
 	0xb9: {IF_Short, IM_None, []OperandType{}, pop}, // This opcode changed to `catch` in V5
 	0xba: {IF_Short, IM_None, []OperandType{}, quit},
@@ -199,6 +197,99 @@
 	0xe7: {IF_Variable, IM_Store, []OperandType{}, random},
 	0xe8: {IF_Variable, IM_None, []OperandType{}, push},
 	0xe9: {IF_Variable, IM_None, []OperandType{}, pull}, // There's an extra argument here in V6
+	0xea: {IF_Variable, IM_None, []OperandType{}, split_window},
+	0xeb: {IF_Variable, IM_None, []OperandType{}, set_window},
+}
+
+// Opcodes that changed form or meaning in later versions. Later revisions take precedence.
+var opcodeRevisions = []opcodeRevision{
+	{3, map[Opcode]InstructionInfo{
+		0xf5: {IF_Variable, IM_None, []OperandType{}, sound_effect},
+	}},
+	{4, map[Opcode]InstructionInfo{
+		0xf1: {IF_Variable, IM_None, []OperandType{}, set_text_style},
+		0xf2: {IF_Variable, IM_None, []OperandType{}, buffer_mode},
+		0xf6: {IF_Variable, IM_Store, []OperandType{}, read_char},
+	}},
+	{5, map[Opcode]InstructionInfo{
+		0x1b:   {IF_Long, IM_None, []OperandType{OT_Small, OT_Small}, set_colour},
+		0x3b:   {IF_Long, IM_None, []OperandType{OT_Small, OT_Variable}, set_colour},
+		0x5b:   {IF_Long, IM_None, []OperandType{OT_Variable, OT_Small}, set_colour},
+		0x7b:   {IF_Long, IM_None, []OperandType{OT_Variable, OT_Variable}, set_colour},
+		0xdb:   {IF_Variable, IM_None, []OperandType{}, set_colour},
+		0xe4:   {IF_Variable, IM_Store, []OperandType{}, read},
+		0xbe0d: {IF_Extended, IM_None, []OperandType{}, set_true_colour},
+	}},
+}
+
+type opcodeRevision struct {
+	minVersion int
+	opcodes    map[Opcode]InstructionInfo
+}
+
+// opcodeTable holds every opcode for one version, indexed by opcodeIndex so decoding doesn't
+// need a map lookup. Unknown opcodes have no handler.
+type opcodeTable [512]InstructionInfo
+
+// opcodeTables are built from opcodes and opcodeRevisions, indexed by version
+var opcodeTables = buildOpcodeTables()
+
+func buildOpcodeTables() [9]opcodeTable {
+	var tables [9]opcodeTable
+	for version := 1; version < len(tables); version++ {
+		for index := range tables[version] {
+			opcode := Opcode(index)
+			if index >= 0x100 {
+				opcode = 0xbe00 | Opcode(index-0x100)
+			}
+
+			info, ok := revisedOpcode(version, opcode)
+			if !ok {
+				continue
+			}
+			tables[version][index] = info
+			handlerNames[reflect.ValueOf(info.Handler).Pointer()] = handlerName(info.Handler)
+		}
+	}
+	return tables
+}
+
+// opcodeIndex places normal opcodes first in an opcodeTable, followed by extended opcodes
+func opcodeIndex(opcode Opcode) (int, bool) {
+	switch {
+	case opcode < 0x100:
+		return int(opcode), true
+	case opcode>>8 == 0xbe:
+		return 0x100 + int(opcode&0xff), true
+	}
+	return 0, false
+}
+
+func lookupOpcode(version int, opcode Opcode) (InstructionInfo, bool) {
+	index, ok := opcodeIndex(opcode)
+	if !ok || version < 1 || version >= len(opcodeTables) {
+		return InstructionInfo{}, false
+	}
+
+	info := opcodeTables[version][index]
+	return info, info.Handler != nil
+}
+
+// revisedOpcode finds the latest revision of an opcode for a version
+func revisedOpcode(version int, opcode Opcode) (InstructionInfo, bool) {
+	for i := len(opcodeRevisions) - 1; i >= 0; i-- {
+		revision := opcodeRevisions[i]
+		if version < revision.minVersion {
+			continue
+		}
+
+		if info, ok := revision.opcodes[opcode]; ok {
+			return info, true
+		}
+	}
+
+	info, ok := opcodes[opcode]
+	return info, ok
 }
 
 func (zmachine ZMachine) readOpcode(address memory.Address) (Opcode, memory.Address) {
@@ -213,163 +304,203 @@ func (zmachine ZMachine) readOpcode(address memory.Address) (Opcode, memory.Addr
 	return Opcode(opcode), next_address
 }
 
-func (zmachine *ZMachine) performBranch(branch Branch, condition bool) bool {
+func (zmachine *ZMachine) performBranch(branch Branch, condition bool) (bool, error) {
 	if branch.Condition == BC_OnTrue && condition ||
 		branch.Condition == BC_OnFalse && !condition {
 		switch branch.Behavior {
 		case BB_Normal:
 			frame, err := zmachine.Stack.Peek()
-			assert.NoError(err, "Error peeking frame stack")
+			if err != nil {
+				return false, err
+			}
 			frame.Counter = branch.Address
-			return true
+			return true, nil
 		case BB_ReturnFalse:
-			zmachine.endCurrentFrame(0)
+			return false, zmachine.endCurrentFrame(0)
 		case BB_ReturnTrue:
-			zmachine.endCurrentFrame(1)
+			return false, zmachine.endCurrentFrame(1)
 		}
 	}
 
-	return false
+	return false, nil
+}
+
+// storeBranch stores a result and then branches on a condition, for instructions that do both
+func (zmachine *ZMachine) storeBranch(instruction Instruction, value word, condition bool) (bool, error) {
+	err := instruction.StoreVariable.Write(value)
+	if err != nil {
+		return false, err
+	}
+	return zmachine.performBranch(instruction.Branch, condition)
 }
 
 func add(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
 
-	instruction.StoreVariable.Write(uint16(a + b))
-	return false, nil
+	return false, instruction.StoreVariable.Write(uint16(a + b))
 }
 
 func and(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := instruction.Operands[0].asWord()
 	b := instruction.Operands[1].asWord()
 
-	instruction.StoreVariable.Write(a & b)
+	return false, instruction.StoreVariable.Write(a & b)
+}
+
+func buffer_mode(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	flag := instruction.Operands[0].asWord()
+
+	zmachine.Screen.SetBuffering(flag != 0)
 	return false, nil
 }
 
 func call(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	packed_address := instruction.Operands[0].asWord()
 	if packed_address == 0 {
-		zmachine.endCurrentFrame(0)
-		return false, nil
+		return false, zmachine.endCurrentFrame(0)
 	}
 
-	routineAddr := zmachine.Memory.RoutinePackedAddress(packed_address)
-	num_locals, next_address := zmachine.Memory.ReadByteNext(routineAddr)
-
-	frame := Frame{ReturnVariable: zmachine.getVariable(0)}
-	frame.Locals = make([]word, 0, num_locals)
-	for range num_locals {
-		var local word
-		if zmachine.Memory.GetVersion() < 5 {
-			local, next_address = zmachine.Memory.ReadWordNext(next_address)
-		} else {
-			local = 0
-		}
-		frame.Locals = append(frame.Locals, local)
+	var buffer [7]word
+	args := buffer[:0]
+	for _, operand := range instruction.Operands[1:] {
+		args = append(args, operand.asWord())
 	}
 
-	for i := 0; i < min(int(num_locals), len(instruction.Operands)-1); i++ {
-		frame.Locals[i] = instruction.Operands[i+1].asWord()
-	}
-
-	frame.Counter = next_address
-
-	frame.DiscardReturn = !instruction.StoresResult()
-	if instruction.StoresResult() {
-		frame.ReturnVariable = instruction.StoreVariable
-	}
-
-	zmachine.Stack.Push(frame)
-	return false, nil // Return false because the previous frame hasn't been updated yet even though there is a new frame
+	frame := Frame{DiscardReturn: !instruction.StoresResult(), ReturnVariable: instruction.StoreVariable}
+	err := zmachine.pushFrame(frame, packed_address, args)
+	return false, err // Return false because the previous frame hasn't been updated yet even though there is a new frame
 }
 
 func clear_attr(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object := instruction.Operands[0].asObjectId()
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	attribute := instruction.Operands[1].asInt()
 
-	GetObject(zmachine.Memory, object).ClearAttribute(attribute)
-
-	return false, nil
+	return false, object.ClearAttribute(attribute)
 }
 
 func dec(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 
-	value := int16(variable.ReadInPlace())
+	current, err := variable.ReadInPlace()
+	if err != nil {
+		return false, err
+	}
+	value := int16(current)
 	value--
-	variable.WriteInPlace(uint16(value))
-
-	return false, nil
+	return false, variable.WriteInPlace(uint16(value))
 }
 
 func dec_chk(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 	condition := int16(instruction.Operands[1].asWord())
 
-	value := int16(variable.ReadInPlace())
+	current, err := variable.ReadInPlace()
+	if err != nil {
+		return false, err
+	}
+	value := int16(current)
 	value--
-	variable.WriteInPlace(uint16(value))
+	err = variable.WriteInPlace(uint16(value))
+	if err != nil {
+		return false, err
+	}
 
-	return zmachine.performBranch(instruction.Branch, value < condition), nil
+	return zmachine.performBranch(instruction.Branch, value < condition)
 }
 
 func div(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
 
-	assert.NotSame(b, 0, "Cannot divide by zero")
+	if b == 0 {
+		return false, ErrDivisionByZero
+	}
 
-	instruction.StoreVariable.Write(uint16(a / b))
-	return false, nil
+	return false, instruction.StoreVariable.Write(uint16(a / b))
 }
 
 func get_child(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object := GetObject(zmachine.Memory, instruction.Operands[0].asObjectId())
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		// Like Frotz, report the error but carry on as though the object has no child
+		jumped, branchErr := zmachine.storeBranch(instruction, 0, false)
+		if branchErr != nil {
+			return jumped, branchErr
+		}
+		return jumped, finished(err)
+	}
 	child := object.Child()
 
-	instruction.StoreVariable.Write(word(child))
-	return zmachine.performBranch(instruction.Branch, child != 0), nil
+	return zmachine.storeBranch(instruction, word(child), child != 0)
 }
 
 func get_next_prop(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	objectId := instruction.Operands[0].asObjectId()
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	propertyId := instruction.Operands[1].asPropertyId()
 
-	object := GetObject(zmachine.Memory, objectId)
-	nextPropId := object.GetNextPropertyId(propertyId)
+	nextPropId, err := object.GetNextPropertyId(propertyId)
+	if err != nil {
+		return false, err
+	}
 
-	instruction.StoreVariable.Write(word(nextPropId))
-	return false, nil
+	return false, instruction.StoreVariable.Write(word(nextPropId))
 }
 
 func get_parent(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object := GetObject(zmachine.Memory, instruction.Operands[0].asObjectId())
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		// Carry on as though the object has no parent, as get_child does
+		storeErr := instruction.StoreVariable.Write(0)
+		if storeErr != nil {
+			return false, storeErr
+		}
+		return false, finished(err)
+	}
 	parent := object.Parent()
 
-	instruction.StoreVariable.Write(word(parent))
-	return false, nil
+	return false, instruction.StoreVariable.Write(word(parent))
 }
 
 func get_prop(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	objectId := instruction.Operands[0].asObjectId()
-	object := GetObject(zmachine.Memory, objectId)
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	propertyId := instruction.Operands[1].asPropertyId()
 
-	data := object.Property(propertyId)
-	dataAsWord := word(data[0]) << 8
-	dataAsWord |= word(data[1])
-	instruction.StoreVariable.Write(dataAsWord)
+	value, err := object.PropertyValue(propertyId)
+	storeErr := instruction.StoreVariable.Write(value)
+	if storeErr != nil {
+		return false, storeErr
+	}
+	if err != nil {
+		// A long property's first word is a usable value, and anything else has stored 0 as a
+		// skipped instruction would, so the error is only reported
+		return false, finished(err)
+	}
 	return false, nil
 }
 
 func get_prop_addr(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object := GetObject(zmachine.Memory, instruction.Operands[0].asObjectId())
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	propertyId := instruction.Operands[1].asPropertyId()
 
-	instruction.StoreVariable.Write(word(object.GetPropertyDataAddress(propertyId)))
-	return false, nil
+	address, err := object.GetPropertyDataAddress(propertyId)
+	if err != nil {
+		return false, err
+	}
+
+	return false, instruction.StoreVariable.Write(word(address))
 }
 
 func get_prop_len(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -383,53 +514,65 @@ func get_prop_len(zmachine *ZMachine, instruction Instruction) (bool, error) {
 		length = int((sizeByte>>6)&0b1) + 1
 	} else {
 		length = int(sizeByte & 0b111111)
+		if length == 0 {
+			length = 64
+		}
 	}
 
-	instruction.StoreVariable.Write(word(length))
-	return false, nil
+	return false, instruction.StoreVariable.Write(word(length))
 }
 
 func get_sibling(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object := GetObject(zmachine.Memory, instruction.Operands[0].asObjectId())
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		// Carry on as though the object has no sibling, as get_child does
+		jumped, branchErr := zmachine.storeBranch(instruction, 0, false)
+		if branchErr != nil {
+			return jumped, branchErr
+		}
+		return jumped, finished(err)
+	}
 	sibling := object.Sibling()
 
-	instruction.StoreVariable.Write(word(sibling))
-	return zmachine.performBranch(instruction.Branch, sibling != 0), nil
+	return zmachine.storeBranch(instruction, word(sibling), sibling != 0)
 }
 
 func inc(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 
-	value := int16(variable.ReadInPlace())
+	current, err := variable.ReadInPlace()
+	if err != nil {
+		return false, err
+	}
+	value := int16(current)
 	value++
-	variable.WriteInPlace(uint16(value))
-
-	return false, nil
+	return false, variable.WriteInPlace(uint16(value))
 }
 
 func inc_chk(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 	condition := int16(instruction.Operands[1].asWord())
 
-	value := int16(variable.ReadInPlace())
+	current, err := variable.ReadInPlace()
+	if err != nil {
+		return false, err
+	}
+	value := int16(current)
 	value++
-	variable.WriteInPlace(uint16(value))
+	err = variable.WriteInPlace(uint16(value))
+	if err != nil {
+		return false, err
+	}
 
-	return zmachine.performBranch(instruction.Branch, value > condition), nil
+	return zmachine.performBranch(instruction.Branch, value > condition)
 }
 
 func insert_obj(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	o := instruction.Operands[0].asObjectId()
-	d := instruction.Operands[1].asObjectId()
-
-	object := GetObject(zmachine.Memory, o)
-	destination := GetObject(zmachine.Memory, d)
-
-	object.SetParent(d)
-	object.SetSibling(destination.Child())
-	destination.SetChild(o)
+	object := instruction.Operands[0].asObjectId()
+	destination := instruction.Operands[1].asObjectId()
 
-	return false, nil
+	err := zmachine.Objects.Move(object, destination)
+	return false, err
 }
 
 func je(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -439,35 +582,40 @@ func je(zmachine *ZMachine, instruction Instruction) (bool, error) {
 		others = append(others, instruction.Operands[i].asWord())
 	}
 
-	return zmachine.performBranch(instruction.Branch, slices.Contains(others, a)), nil
+	return zmachine.performBranch(instruction.Branch, slices.Contains(others, a))
 }
 
 func jg(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
 
-	return zmachine.performBranch(instruction.Branch, a > b), nil
+	return zmachine.performBranch(instruction.Branch, a > b)
 }
 
 func jin(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	a := GetObject(zmachine.Memory, instruction.Operands[0].asObjectId())
+	a, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	b := instruction.Operands[1].asObjectId()
 
-	return zmachine.performBranch(instruction.Branch, a.Parent() == b), nil
+	return zmachine.performBranch(instruction.Branch, a.Parent() == b)
 }
 
 func jl(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
 
-	return zmachine.performBranch(instruction.Branch, a < b), nil
+	return zmachine.performBranch(instruction.Branch, a < b)
 }
 
 func jump(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	offset := instruction.Operands[0].asInt()
 
 	frame, err := zmachine.Stack.Peek()
-	assert.NoError(err, "Error peeking frame stack")
+	if err != nil {
+		return false, err
+	}
 	frame.Counter = instruction.NextAddress.OffsetBytes(offset - 2)
 	return true, nil
 }
@@ -475,16 +623,17 @@ func jump(zmachine *ZMachine, instruction Instruction) (bool, error) {
 func jz(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := instruction.Operands[0].asWord()
 
-	return zmachine.performBranch(instruction.Branch, a == 0), nil
+	return zmachine.performBranch(instruction.Branch, a == 0)
 }
 
 func load(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 
-	value := variable.ReadInPlace()
-	instruction.StoreVariable.Write(value)
-
-	return false, nil
+	value, err := variable.ReadInPlace()
+	if err != nil {
+		return false, err
+	}
+	return false, instruction.StoreVariable.Write(value)
 }
 
 func loadb(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -492,9 +641,7 @@ func loadb(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	index := instruction.Operands[1].asInt()
 
 	value := zmachine.Memory.ReadByte(array.OffsetBytes(index))
-	instruction.StoreVariable.Write(word(value))
-
-	return false, nil
+	return false, instruction.StoreVariable.Write(word(value))
 }
 
 func loadw(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -504,26 +651,25 @@ func loadw(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	address := array.OffsetWords(word_index)
 	value := zmachine.Memory.ReadWord(address)
 
-	instruction.StoreVariable.Write(value)
-	return false, nil
+	return false, instruction.StoreVariable.Write(value)
 }
 
 func mul(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
 
-	instruction.StoreVariable.Write(uint16(a * b))
-	return false, nil
+	return false, instruction.StoreVariable.Write(uint16(a * b))
 }
 
 func mod(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
 
-	assert.NotSame(b, 0, "Cannot mod by 0")
+	if b == 0 {
+		return false, ErrDivisionByZero
+	}
 
-	instruction.StoreVariable.Write(uint16(a % b))
-	return false, nil
+	return false, instruction.StoreVariable.Write(uint16(a % b))
 }
 
 func new_line(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -534,34 +680,30 @@ func new_line(zmachine *ZMachine, instruction Instruction) (bool, error) {
 func not(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := instruction.Operands[0].asWord()
 
-	instruction.StoreVariable.Write(^a)
-	return false, nil
+	return false, instruction.StoreVariable.Write(^a)
 }
 
 func or(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := instruction.Operands[0].asWord()
 	b := instruction.Operands[1].asWord()
 
-	instruction.StoreVariable.Write(a | b)
-	return false, nil
+	return false, instruction.StoreVariable.Write(a | b)
 }
 
 func pop(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	frame, err := zmachine.Stack.Peek()
-	assert.NoError(err, "Error popping frame stack")
-	_, err = frame.Stack.Pop()
-	assert.NoError(err, "Error popping local stack")
-
-	return false, nil
+	_, err := zmachine.Stack.PopValue()
+	return false, err
 }
 
 func print(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	zstr := zmachine.Memory.GetZString(instruction.NextAddress)
 	next_address := instruction.NextAddress.OffsetBytes(zstr.LenBytes())
 
-	parser := zstring.NewParser(zmachine.Charset, zmachine.Memory.GetAbbreviation)
+	parser := zstring.NewParser(zmachine.Charset, zmachine.Unicode, zmachine.Memory.GetVersion(), zmachine.Memory.GetAbbreviation)
 	str, err := parser.Parse(zstr)
-	assert.NoError(err, "Error parsing print ZString")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing print ZString: %w", err)
+	}
 
 	zmachine.Screen.PrintText(str)
 	if zmachine.Debug {
@@ -574,16 +716,18 @@ func print(zmachine *ZMachine, instruction Instruction) (bool, error) {
 		Condition: BC_OnTrue,
 	}
 
-	return zmachine.performBranch(branch, true), nil
+	return zmachine.performBranch(branch, true)
 }
 
 func print_addr(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	address := instruction.Operands[0].asAddress()
 	zstr := zmachine.Memory.GetZString(address)
 
-	parser := zstring.NewParser(zmachine.Charset, zmachine.Memory.GetAbbreviation)
+	parser := zstring.NewParser(zmachine.Charset, zmachine.Unicode, zmachine.Memory.GetVersion(), zmachine.Memory.GetAbbreviation)
 	str, err := parser.Parse(zstr)
-	assert.NoError(err, "Error parsing print ZString")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing print ZString: %w", err)
+	}
 
 	zmachine.Screen.PrintText(str)
 	if zmachine.Debug {
@@ -594,10 +738,9 @@ func print_addr(zmachine *ZMachine, instruction Instruction) (bool, error) {
 }
 
 func print_char(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	a := instruction.Operands[0].asByte()
+	a := zstring.ZSCII(instruction.Operands[0].asWord())
 
-	// TODO: This should convert to ZSCII rather than ASCII/Unicode
-	zmachine.Screen.PrintText(string(a))
+	zmachine.Screen.PrintText(zmachine.Unicode.Translate(a))
 	if zmachine.Debug {
 		fmt.Println()
 	}
@@ -617,12 +760,18 @@ func print_num(zmachine *ZMachine, instruction Instruction) (bool, error) {
 }
 
 func print_obj(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	o := GetObject(zmachine.Memory, instruction.Operands[0].asObjectId())
-	parser := zstring.NewParser(zmachine.Charset, zmachine.Memory.GetAbbreviation)
+	id := instruction.Operands[0].asObjectId()
+	o, err := zmachine.Objects.Get(id)
+	if err != nil {
+		return false, err
+	}
+	parser := zstring.NewParser(zmachine.Charset, zmachine.Unicode, zmachine.Memory.GetVersion(), zmachine.Memory.GetAbbreviation)
 
 	zstr := o.ShortName()
 	str, err := parser.Parse(zstr)
-	assert.NoError(err, "Error parsing object short name")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing short name of %s: %w", describeObject(zmachine.symbols, id), err)
+	}
 	zmachine.Screen.PrintText(fmt.Sprintf("%v", str))
 	if zmachine.Debug {
 		fmt.Println()
@@ -633,10 +782,12 @@ func print_obj(zmachine *ZMachine, instruction Instruction) (bool, error) {
 
 func print_paddr(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	address := zmachine.Memory.StringPackedAddress(instruction.Operands[0].asWord())
-	parser := zstring.NewParser(zmachine.Charset, zmachine.Memory.GetAbbreviation)
+	parser := zstring.NewParser(zmachine.Charset, zmachine.Unicode, zmachine.Memory.GetVersion(), zmachine.Memory.GetAbbreviation)
 	zstr := zmachine.Memory.GetZString(address)
 	str, err := parser.Parse(zstr)
-	assert.NoError(err, "Error parsing paddr ZString")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing paddr ZString: %w", err)
+	}
 	zmachine.Screen.PrintText(str)
 	if zmachine.Debug {
 		fmt.Println()
@@ -648,48 +799,45 @@ func print_paddr(zmachine *ZMachine, instruction Instruction) (bool, error) {
 func print_ret(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	zstr := zmachine.Memory.GetZString(instruction.NextAddress)
 
-	parser := zstring.NewParser(zmachine.Charset, zmachine.Memory.GetAbbreviation)
+	parser := zstring.NewParser(zmachine.Charset, zmachine.Unicode, zmachine.Memory.GetVersion(), zmachine.Memory.GetAbbreviation)
 	str, err := parser.Parse(zstr)
-	assert.NoError(err, "Error parsing print ZString")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing print ZString: %w", err)
+	}
 
 	zmachine.Screen.PrintText(str)
 	zmachine.Screen.PrintText("\n")
 
-	zmachine.endCurrentFrame(1)
-	return true, nil
+	return true, zmachine.endCurrentFrame(1)
 }
 
 func pull(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 
-	frame, err := zmachine.Stack.Peek()
-	assert.NoError(err, "Error peeking frame stack")
-	value, err := frame.Stack.Pop()
-	assert.NoError(err, "Error popping local stack")
-	variable.WriteInPlace(value)
-
-	return false, nil
+	value, err := zmachine.Stack.PopValue()
+	if err != nil {
+		return false, err
+	}
+	return false, variable.WriteInPlace(value)
 }
 
 func push(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	value := instruction.Operands[0].asWord()
 
-	frame, err := zmachine.Stack.Peek()
-	assert.NoError(err, "Error peeking frame stack")
-	frame.Stack.Push(value)
-
-	return false, nil
+	err := zmachine.Stack.PushValue(value)
+	return false, err
 }
 
 func put_prop(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object_index := instruction.Operands[0].asObjectId()
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	property_index := instruction.Operands[1].asPropertyId()
-	value := instruction.Operands[2].asBytes()
-
-	object := GetObject(zmachine.Memory, object_index)
-	object.SetProperty(property_index, value)
+	value := instruction.Operands[2].asWord()
 
-	return false, nil
+	err = object.SetProperty(property_index, value)
+	return false, err
 }
 
 func quit(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -701,111 +849,263 @@ func random(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	r := int16(instruction.Operands[0].asWord())
 
 	if r > 0 {
-		value := zmachine.Random.IntN(int(r)) + 1
-		instruction.StoreVariable.Write(word(value))
-	} else {
-		seed := uint64(r)
-		if r == 0 {
-			seed = uint64(time.Now().UnixMilli())
-		}
-		zmachine.Random = rand.New(rand.NewPCG(seed, seed))
-		instruction.StoreVariable.Write(0)
+		value := zmachine.Random.Next(int(r))
+		return false, instruction.StoreVariable.Write(word(value))
 	}
 
-	return false, nil
+	zmachine.Random.Reseed(-int(r))
+	return false, instruction.StoreVariable.Write(0)
 }
 
 func read(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	text := instruction.Operands[0].asAddress()
-	parse := instruction.Operands[1].asAddress()
-	// TODO: In V4, there are 2 additional parameters here
+
+	version := zmachine.Memory.GetVersion()
+	maxTextLength, nextAddress := zmachine.Memory.ReadByteNext(text)
+	preload := []zstring.ZSCII{}
+
+	if version >= 5 {
+		// V5+ buffers hold the current length in byte 1, and may already contain text
+		preloadLength, nextAddress := zmachine.Memory.ReadByteNext(nextAddress)
+		for _, b := range zmachine.Memory.GetBytes(nextAddress, int(preloadLength)) {
+			preload = append(preload, zstring.ZSCII(b))
+		}
+	} else {
+		maxTextLength++ // Initial value is maximum length - 1, so we increment
+	}
+
+	options := screen.ReadOptions{
+		Accept:       zmachine.acceptsInput,
+		Preload:      zmachine.toUnicode(preload),
+		MaxLength:    int(maxTextLength),
+		IsTerminator: zmachine.isTerminatingCharacter,
+		Complete:     zmachine.completeWord,
+	}
+
+	var interruptErr error
+	if version >= 4 && len(instruction.Operands) >= 4 {
+		options.Interval, options.OnInterrupt = zmachine.timedInput(instruction.Operands[2], instruction.Operands[3], &interruptErr)
+	}
+
+	// Keep the state at each prompt so /undo can return to the one before
+	prompt := zmachine.snapshot()
+	zmachine.undo, zmachine.prompt = zmachine.prompt, &prompt
 
 	// TODO: Redisplay Status Line
-	str := zmachine.Screen.Read()
-	zmachine.Screen.PrintText(str)
+	str, terminator, err := zmachine.nextInput(options)
+	for err == nil && terminator == zstring.ZSCII_NewLine && isMetaCommand(str) {
+		zmachine.Screen.PrintText("\n")
+		zmachine.reading = &instruction
+		restored := zmachine.runMetaCommand(str)
+		zmachine.reading = nil
+		if restored {
+			// Restoring a state sets where the game carries on, so there is nothing left to do
+			return true, nil
+		}
 
+		options.Preload = ""
+		str, terminator, err = zmachine.nextInput(options)
+	}
+	if err != nil {
+		return false, err
+	}
+	if interruptErr != nil {
+		return false, interruptErr
+	}
+
+	if terminator == zstring.ZSCII_NewLine {
+		zmachine.Screen.PrintText("\n")
+	}
+
+	return false, zmachine.storeInput(instruction, zmachine.toInputZSCII(str), terminator)
+}
+
+// storeInput finishes a read with the given input, filling in its text and parse buffers and
+// storing the terminator
+func (zmachine *ZMachine) storeInput(instruction Instruction, input []zstring.ZSCII, terminator zstring.ZSCII) error {
+	text := instruction.Operands[0].asAddress()
+	parse := instruction.Operands[1].asAddress()
+
+	version := zmachine.Memory.GetVersion()
 	maxTextLength, nextAddress := zmachine.Memory.ReadByteNext(text)
-	maxTextLength++ // Initial value is maximum length - 1, so we increment
-	str = strings.ToLower(str[:min(len(str), int(maxTextLength))])
-	str += "\x00"
-	zmachine.Memory.SetBytes(nextAddress, []byte(str))
+	textOffset := 1
+	if version >= 5 {
+		textOffset = 2
+	} else {
+		maxTextLength++ // Initial value is maximum length - 1, so we increment
+	}
+	input = input[:min(len(input), int(maxTextLength))]
 
-	zmachine.Screen.PrintText("\n")
+	data := make([]byte, 0, len(input)+1)
+	for _, zc := range input {
+		data = append(data, byte(zc))
+	}
 
-	// TODO: Perform lexical analysis
-	_, nextAddress = zmachine.Memory.ReadByteNext(parse)
-	// maxLexicalWords, nextAddress := zmachine.Memory.ReadByteNext(parse)
+	if version >= 5 {
+		nextAddress = zmachine.Memory.WriteByte(nextAddress, byte(len(input)))
+	} else {
+		data = append(data, 0x00)
+	}
+	zmachine.Memory.SetBytes(nextAddress, data)
 
-	return false, nil
+	if parse != 0 {
+		err := zmachine.tokenise(input, textOffset, parse)
+		if err != nil {
+			return err
+		}
+	}
+
+	if instruction.StoresResult() {
+		return instruction.StoreVariable.Write(word(terminator))
+	}
+	return nil
 }
 
-func remove_obj(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	oid := instruction.Operands[0].asObjectId()
-	object := GetObject(zmachine.Memory, oid)
+func read_char(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	// The first operand is always 1, and only exists for historical reasons
+	options := screen.ReadOptions{Accept: zmachine.acceptsInput}
 
-	parentId := object.Parent()
-	if parentId == 0 {
-		return false, nil
+	var interruptErr error
+	if len(instruction.Operands) >= 3 {
+		options.Interval, options.OnInterrupt = zmachine.timedInput(instruction.Operands[1], instruction.Operands[2], &interruptErr)
 	}
 
-	parent := GetObject(zmachine.Memory, object.Parent())
-	object.SetParent(0)
-
-	parentChild := parent.Child()
-	if parentChild == oid {
-		parent.SetChild(object.Sibling())
-		return false, nil
+	r, zc, err := zmachine.Screen.ReadChar(options)
+	if err != nil {
+		return false, err
+	}
+	if interruptErr != nil {
+		return false, interruptErr
 	}
 
-	if parentChild != 0 {
-		siblingId := parentChild
-		for siblingId != 0 {
-			sibling := GetObject(zmachine.Memory, siblingId)
-			siblingSibling := sibling.Sibling()
-			if siblingSibling == oid {
-				sibling.SetSibling(object.Sibling())
-				break
-			}
-			siblingId = siblingSibling
+	if zc == zstring.ZSCII_Null && r != 0 {
+		zc, err = zmachine.Unicode.ToZSCII(r)
+		if err != nil {
+			return false, fmt.Errorf("Error converting input to ZSCII: %w", err)
 		}
 	}
 
-	return false, nil
+	return false, instruction.StoreVariable.Write(word(zc))
+}
+
+func remove_obj(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	err := zmachine.Objects.Remove(instruction.Operands[0].asObjectId())
+	return false, err
 }
 
 func ret(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	value := instruction.Operands[0].asWord()
 
-	zmachine.endCurrentFrame(value)
-	return true, nil
+	return true, zmachine.endCurrentFrame(value)
 }
 
 func ret_popped(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	frame, err := zmachine.Stack.Peek()
-	assert.NoError(err, "Error peeking frame stack")
-	value, err := frame.Stack.Pop()
-	assert.NoError(err, "Error popping local stack")
+	value, err := zmachine.Stack.PopValue()
+	if err != nil {
+		return false, err
+	}
 
-	zmachine.endCurrentFrame(value)
-	return true, nil
+	return true, zmachine.endCurrentFrame(value)
 }
 
 func rfalse(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	zmachine.endCurrentFrame(0)
-	return true, nil
+	return true, zmachine.endCurrentFrame(0)
 }
 
 func rtrue(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	zmachine.endCurrentFrame(1)
-	return true, nil
+	return true, zmachine.endCurrentFrame(1)
 }
 
 func set_attr(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object := instruction.Operands[0].asObjectId()
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	attribute := instruction.Operands[1].asInt()
 
-	GetObject(zmachine.Memory, object).SetAttribute(attribute)
+	return false, object.SetAttribute(attribute)
+}
+
+func set_colour(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	foreground := int(int16(instruction.Operands[0].asWord()))
+	background := int(int16(instruction.Operands[1].asWord()))
+
+	zmachine.Screen.SetColour(foreground, background)
+	return false, nil
+}
+
+func set_text_style(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	style := screen.TextStyle(instruction.Operands[0].asByte())
+
+	zmachine.Screen.SetTextStyle(style)
+	return false, nil
+}
+
+func set_true_colour(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	foreground := int(int16(instruction.Operands[0].asWord()))
+	background := int(int16(instruction.Operands[1].asWord()))
 
+	zmachine.Screen.SetTrueColour(foreground, background)
+	return false, nil
+}
+
+func set_window(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	window := screen.Window(instruction.Operands[0].asWord())
+
+	zmachine.Screen.SetWindow(window)
+	return false, nil
+}
+
+func sound_effect(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	operands := instruction.Operands
+	if len(operands) == 0 {
+		zmachine.Sound.Beep(true)
+		return false, nil
+	}
+
+	number := operands[0].asInt()
+	if number == soundHighBeep || number == soundLowBeep {
+		zmachine.Sound.Beep(number == soundHighBeep)
+		return false, nil
+	}
+
+	effect := soundEffectStart
+	if len(operands) > 1 {
+		effect = operands[1].asInt()
+	}
+
+	// Stories are expected to carry on without sounds the player can't find, so failures to
+	// play them are ignored
+	switch effect {
+	case soundEffectPrepare:
+		_ = zmachine.Sound.Prepare(number)
+	case soundEffectStart:
+		version := zmachine.Memory.GetVersion()
+		volume, repeats := soundVolume(0xff, version)
+		if len(operands) > 2 {
+			volume, repeats = soundVolume(operands[2].asWord(), version)
+		}
+
+		// Only V5+ stories can be told when the sound has finished
+		var routine word
+		if len(operands) > 3 && version >= 5 {
+			routine = operands[3].asWord()
+		}
+
+		_ = zmachine.Sound.Start(number, volume, repeats, zmachine.soundFinished(routine))
+	case soundEffectStop:
+		zmachine.Sound.Stop(number)
+	case soundEffectFinish:
+		zmachine.Sound.Finish(number)
+	}
+
+	return false, nil
+}
+
+func split_window(zmachine *ZMachine, instruction Instruction) (bool, error) {
+	lines := instruction.Operands[0].asInt()
+
+	zmachine.Screen.SplitWindow(lines)
 	return false, nil
 }
 
@@ -813,8 +1113,7 @@ func store(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	variable := zmachine.getVariable(instruction.Operands[0].asVarNum())
 	value := instruction.Operands[1].asWord()
 
-	variable.WriteInPlace(value)
-	return false, nil
+	return false, variable.WriteInPlace(value)
 }
 
 func storeb(zmachine *ZMachine, instruction Instruction) (bool, error) {
@@ -840,43 +1139,31 @@ func storew(zmachine *ZMachine, instruction Instruction) (bool, error) {
 func sub(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	a := int16(instruction.Operands[0].asWord())
 	b := int16(instruction.Operands[1].asWord())
-	instruction.StoreVariable.Write(uint16(a - b))
-	return false, nil
+	return false, instruction.StoreVariable.Write(uint16(a - b))
 }
 
 func test(zmachine *ZMachine, instruction Instruction) (bool, error) {
 	bitmask := instruction.Operands[0].asWord()
 	flags := instruction.Operands[1].asWord()
 
-	return zmachine.performBranch(instruction.Branch, bitmask&flags == flags), nil
+	return zmachine.performBranch(instruction.Branch, bitmask&flags == flags)
 }
 
 func test_attr(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	object_index := instruction.Operands[0].asObjectId()
+	object, err := zmachine.Objects.Get(instruction.Operands[0].asObjectId())
+	if err != nil {
+		return false, err
+	}
 	attribute_index := instruction.Operands[1].asInt()
 
-	object := GetObject(zmachine.Memory, object_index)
-
-	return zmachine.performBranch(instruction.Branch, object.HasAttribute(attribute_index)), nil
+	hasAttribute, err := object.HasAttribute(attribute_index)
+	if err != nil {
+		return false, err
+	}
+	return zmachine.performBranch(instruction.Branch, hasAttribute)
 }
 
 func verify(zmachine *ZMachine, instruction Instruction) (bool, error) {
-	// TODO: Implement checksum verification. The below logic should be similar to what is needed
-	// but doesn't actually pass CZECH right now. Also see memory.OriginalFileState.
-
-	// fileLength := int(zmachine.Memory.ReadWord(memory.Addr_ROM_W_FileLength))
-	// checksum := int(zmachine.Memory.ReadWord(memory.Addr_ROM_W_Checksum))
-
-	// sum := 0
-	// filemem, err := zmachine.Memory.OriginalFileState()
-	// assert.NoError(err, "Error validating checksum")
-	// data := filemem.GetBytes(memory.Address(0x40), fileLength-0x40)
-	// for _, b := range data {
-	// 	sum += int(b)
-	// }
-
-	// sum %= 0x10000
-
-	// return zmachine.performBranch(instruction.Branch, sum == checksum), nil
-	return zmachine.performBranch(instruction.Branch, true), nil
+	m := zmachine.Memory
+	return zmachine.performBranch(instruction.Branch, m.CalculateChecksum() == m.GetChecksum())
 }
@@ -0,0 +1,450 @@
+package zmachine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/screen"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Stories report what happened by storing to these globals
+const (
+	g0 byte = 0x10
+	g1 byte = 0x11
+	g2 byte = 0x12
+)
+
+// recordingSoundPlayer notes what sound_effect asked for instead of playing anything
+type recordingSoundPlayer struct {
+	calls []string
+}
+
+func (p *recordingSoundPlayer) Sampled() bool { return true }
+func (p *recordingSoundPlayer) Beep(high bool) {
+	p.calls = append(p.calls, fmt.Sprintf("beep %t", high))
+}
+func (p *recordingSoundPlayer) Prepare(number int) error { return nil }
+func (p *recordingSoundPlayer) Stop(number int) {
+	p.calls = append(p.calls, fmt.Sprintf("stop %d", number))
+}
+func (p *recordingSoundPlayer) Finish(number int) {}
+
+func (p *recordingSoundPlayer) Start(number int, volume int, repeats int, done func()) error {
+	p.calls = append(p.calls, fmt.Sprintf("start %d at %d", number, volume))
+	return nil
+}
+
+// runStory runs a story on a simulated screen until it quits, typing the input lines for read
+// and pressing the keys for read_char. It returns the machine, the lower window text, the errors
+// reported along the way, which the story carries on past, and what the screen showed as it quit.
+func runStory(t *testing.T, story teststory.Story, input []string, keys string) (*ZMachine, string, []string, tcell.SimulationScreen) {
+	t.Helper()
+
+	// Every story quits at the end, so none of them run on into whatever follows
+	story.Main = append(slices.Clone(story.Main), teststory.Op0(0x0a))
+	m, err := story.Memory()
+	if err != nil {
+		t.Fatalf("Error assembling story: %v", err)
+	}
+
+	s, sim := screen.NewSimulationScreen(80, 25)
+	s.Paging = false
+	output := &bytes.Buffer{}
+	s.Transcript = output
+
+	zmachine, err := NewZMachine(m, s)
+	testassert.NoError(t, err)
+	zmachine.Sound = &recordingSoundPlayer{}
+	zmachine.script = input
+	zmachine.ErrorLevel = EL_Ignore
+	reported := []string{}
+	var shown tcell.SimulationScreen
+
+	halted := false
+	zmachine.exit = func(code int) { halted = true }
+
+	for _, r := range keys {
+		sim.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+
+	for steps := 0; !halted; steps++ {
+		if steps == 10000 {
+			t.Fatal("Story never quit")
+		}
+
+		// Quitting ends the screen, which clears the simulation
+		frame, err := zmachine.Stack.Peek()
+		testassert.NoError(t, err)
+		if next, _, err := zmachine.readInstruction(frame.Counter); err == nil && next.Name() == "quit" {
+			shown = snapshotScreen(t, zmachine.Screen, sim)
+		}
+
+		err = zmachine.executeNextInstruction()
+		if err == nil {
+			continue
+		}
+
+		zerr := &ZMachineError{}
+		if errors.As(err, &zerr) {
+			reported = append(reported, zerr.Err.Error())
+		}
+		if zmachine.handleError(err) != nil {
+			t.Fatalf("Error running story: %v", err)
+		}
+	}
+	return zmachine, output.String(), reported, shown
+}
+
+// snapshotScreen copies what the simulation shows, once any buffered word has been drawn
+func snapshotScreen(t *testing.T, s *screen.Screen, sim tcell.SimulationScreen) tcell.SimulationScreen {
+	t.Helper()
+
+	s.Flush()
+	cells, width, height := sim.GetContents()
+	snapshot := tcell.NewSimulationScreen("")
+	testassert.NoError(t, snapshot.Init())
+	snapshot.SetSize(width, height)
+	for i, cell := range cells {
+		if len(cell.Runes) > 0 {
+			snapshot.SetContent(i%width, i/width, cell.Runes[0], cell.Runes[1:], cell.Style)
+		}
+	}
+	return snapshot
+}
+
+// screenRow is the text on a row of the screen, without trailing spaces
+func screenRow(sim tcell.SimulationScreen, y int) string {
+	width, _ := sim.Size()
+	builder := strings.Builder{}
+	for x := 0; x < width; x++ {
+		r, _, _, _ := sim.GetContent(x, y)
+		builder.WriteRune(r)
+	}
+	return strings.TrimRight(builder.String(), " ")
+}
+
+// checkCell compares the style a cell was drawn in
+func checkCell(t *testing.T, sim tcell.SimulationScreen, x int, y int, foreground tcell.Color, background tcell.Color, attrs tcell.AttrMask) {
+	t.Helper()
+
+	_, _, style, _ := sim.GetContent(x, y)
+	fg, bg, actual := style.Decompose()
+	testassert.Same(t, foreground, fg)
+	testassert.Same(t, background, bg)
+	testassert.Same(t, attrs, actual)
+}
+
+// storeGlobal sets a global to a small value
+func storeGlobal(variable byte, value byte) teststory.Instruction {
+	return teststory.Op2(0x0d, teststory.Small(variable), teststory.Small(value))
+}
+
+// branches follows an instruction with code that sets g0 to 1 if it branches, or 2 if it doesn't
+func branches(instruction teststory.Instruction) []teststory.Instruction {
+	return []teststory.Instruction{
+		instruction.Branch("taken", true),
+		storeGlobal(g0, 2),
+		teststory.Op0(0x0a),
+		teststory.Label("taken"),
+		storeGlobal(g0, 1),
+	}
+}
+
+// testObjects is a box holding a ball and a bat, and a table standing on its own
+var testObjects = []teststory.Object{
+	{Name: "box", Attributes: []int{3}, Child: 2, Properties: []teststory.Property{
+		{Number: 5, Data: []byte{0x56}},
+		{Number: 10, Data: []byte{0x12, 0x34}},
+		{Number: 20, Data: []byte{1, 2, 3}},
+	}},
+	{Name: "ball", Parent: 1, Sibling: 3},
+	{Name: "bat", Parent: 1},
+	{Name: "table"},
+}
+
+type opcodeTest struct {
+	story   teststory.Story
+	input   []string      // Lines typed for read
+	keys    string        // Keys pressed for read_char
+	globals map[byte]word // Expected values of globals once the story quits
+	output  string        // Expected lower window text
+	errors  []string      // Errors the story is expected to report, in order
+	check   func(t *testing.T, zmachine *ZMachine)
+	screen  func(t *testing.T, sim tcell.SimulationScreen) // Checks what the screen showed as the story quit
+}
+
+// opcodeTests are named for the handler they test, followed by what they cover
+func opcodeTests() map[string]opcodeTest {
+	type Story = teststory.Story
+	type Routine = teststory.Routine
+	type Code = []teststory.Instruction
+	op0, op1, op2, opVar, opExt := teststory.Op0, teststory.Op1, teststory.Op2, teststory.OpVar, teststory.OpExt
+	large, small, v := teststory.Large, teststory.Small, teststory.Var
+
+	textBuffer := make([]byte, 22)
+	textBuffer[0] = 20
+	parseBuffer := make([]byte, 2+4*4)
+	parseBuffer[0] = 4
+	readArrays := map[string][]byte{"text": textBuffer, "parse": parseBuffer}
+	readDictionary := teststory.Dictionary{Separators: ",", Words: []string{"take", "lamp"}}
+
+	return map[string]opcodeTest{
+		// Arithmetic is signed
+		"add": {story: Story{Main: Code{op2(0x14, small(3), large(0xfffb)).Store(g0)}}, globals: map[byte]word{g0: 0xfffe}},
+		"sub": {story: Story{Main: Code{op2(0x15, small(3), small(5)).Store(g0)}}, globals: map[byte]word{g0: 0xfffe}},
+		"mul": {story: Story{Main: Code{op2(0x16, large(0xfffd), small(7)).Store(g0)}}, globals: map[byte]word{g0: 0xffeb}},
+		"div": {story: Story{Main: Code{op2(0x17, large(0xfff9), small(2)).Store(g0)}}, globals: map[byte]word{g0: 0xfffd}},
+		"mod": {story: Story{Main: Code{op2(0x18, large(0xfff9), small(2)).Store(g0)}}, globals: map[byte]word{g0: 0xffff}},
+		"div by zero": {
+			story:   Story{Globals: map[byte]word{g0: 9}, Main: Code{op2(0x17, small(7), small(0)).Store(g0)}},
+			globals: map[byte]word{g0: 0},
+			errors:  []string{"Division by zero"},
+		},
+		"mod by zero": {
+			story:   Story{Globals: map[byte]word{g0: 9}, Main: Code{op2(0x18, small(7), small(0)).Store(g0)}},
+			globals: map[byte]word{g0: 0},
+			errors:  []string{"Division by zero"},
+		},
+		"and":             {story: Story{Main: Code{op2(0x09, large(0xff0f), large(0x0ff0)).Store(g0)}}, globals: map[byte]word{g0: 0x0f00}},
+		"or":              {story: Story{Main: Code{op2(0x08, large(0xff00), large(0x0ff0)).Store(g0)}}, globals: map[byte]word{g0: 0xfff0}},
+		"not":             {story: Story{Main: Code{op1(0x0f, large(0x0ff0)).Store(g0)}}, globals: map[byte]word{g0: 0xf00f}},
+		"test all flags":  {story: Story{Main: branches(op2(0x07, large(0x0f0f), large(0x0303)))}, globals: map[byte]word{g0: 1}},
+		"test some flags": {story: Story{Main: branches(op2(0x07, large(0x0f0f), small(0x30)))}, globals: map[byte]word{g0: 2}},
+
+		// Branches
+		"je any operand":      {story: Story{Main: branches(op2(0x01, small(5), small(1), small(5)))}, globals: map[byte]word{g0: 1}},
+		"je unequal":          {story: Story{Main: branches(op2(0x01, small(5), small(6)))}, globals: map[byte]word{g0: 2}},
+		"jl signed":           {story: Story{Main: branches(op2(0x02, large(0xffff), small(1)))}, globals: map[byte]word{g0: 1}},
+		"jg signed":           {story: Story{Main: branches(op2(0x03, large(0xffff), small(1)))}, globals: map[byte]word{g0: 2}},
+		"jz":                  {story: Story{Main: branches(op1(0x00, small(0)))}, globals: map[byte]word{g0: 1}},
+		"dec_chk":             {story: Story{Globals: map[byte]word{g1: 3}, Main: branches(op2(0x04, small(g1), small(3)))}, globals: map[byte]word{g0: 1, g1: 2}},
+		"inc_chk":             {story: Story{Globals: map[byte]word{g1: 3}, Main: branches(op2(0x05, small(g1), small(3)))}, globals: map[byte]word{g0: 1, g1: 4}},
+		"jin":                 {story: Story{Objects: testObjects, Main: branches(op2(0x06, small(2), small(1)))}, globals: map[byte]word{g0: 1}},
+		"jin other parent":    {story: Story{Objects: testObjects, Main: branches(op2(0x06, small(4), small(1)))}, globals: map[byte]word{g0: 2}},
+		"test_attr":           {story: Story{Objects: testObjects, Main: branches(op2(0x0a, small(1), small(3)))}, globals: map[byte]word{g0: 1}},
+		"get_child":           {story: Story{Objects: testObjects, Main: branches(op1(0x02, small(1)).Store(g1))}, globals: map[byte]word{g0: 1, g1: 2}},
+		"get_sibling of last": {story: Story{Objects: testObjects, Main: branches(op1(0x01, small(3)).Store(g1))}, globals: map[byte]word{g0: 2, g1: 0}},
+		"get_child of object 0": {
+			story:   Story{Objects: testObjects, Globals: map[byte]word{g1: 9}, Main: branches(op1(0x02, small(0)).Store(g1))},
+			globals: map[byte]word{g0: 2, g1: 0},
+			errors:  []string{"Object 0 is not an object"},
+		},
+		"get_sibling of object 0 branches on no sibling": {
+			story: Story{Objects: testObjects, Globals: map[byte]word{g1: 9}, Main: Code{
+				op1(0x01, small(0)).Store(g1).Branch("none", false), storeGlobal(g0, 2), op0(0x0a),
+				teststory.Label("none"), storeGlobal(g0, 1),
+			}},
+			globals: map[byte]word{g0: 1, g1: 0},
+			errors:  []string{"Object 0 is not an object"},
+		},
+		"get_parent of object 0 pushes once": {
+			// The second add finds the stack empty, so get_parent pushed exactly one 0
+			story: Story{Objects: testObjects, Globals: map[byte]word{g2: 9}, Main: Code{
+				op1(0x03, small(0)).Store(0), op2(0x14, v(0), small(5)).Store(g1), op2(0x14, v(0), small(5)).Store(g2),
+			}},
+			globals: map[byte]word{g1: 5, g2: 0},
+			errors:  []string{"Object 0 is not an object", "Stack underflow: the stack of routine 0 is empty"},
+		},
+		"jz returning": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("check")).Store(g0)},
+			Routines: map[string]Routine{"check": {Code: Code{op1(0x00, small(0)).BranchReturn(true, true), op0(0x01)}}},
+		}, globals: map[byte]word{g0: 1}},
+
+		// Variables and the stack
+		"store":          {story: Story{Main: Code{op2(0x0d, small(g0), large(0x1234))}}, globals: map[byte]word{g0: 0x1234}},
+		"store in place": {story: Story{Main: Code{opVar(0x08, small(7)), opVar(0x08, small(1)), op2(0x0d, small(0), small(2)), opVar(0x09, small(g0)), opVar(0x09, small(g1))}}, globals: map[byte]word{g0: 2, g1: 7}},
+		"load":           {story: Story{Globals: map[byte]word{g1: 0x4321}, Main: Code{op1(0x0e, small(g1)).Store(g0)}}, globals: map[byte]word{g0: 0x4321}},
+		"inc wraps":      {story: Story{Globals: map[byte]word{g0: 0xffff}, Main: Code{op1(0x05, small(g0))}}, globals: map[byte]word{g0: 0}},
+		"dec wraps":      {story: Story{Main: Code{op1(0x06, small(g0))}}, globals: map[byte]word{g0: 0xffff}},
+		"push":           {story: Story{Main: Code{opVar(0x08, large(0x1234)), op1(0x0e, small(0)).Store(g0)}}, globals: map[byte]word{g0: 0x1234}},
+		"pull":           {story: Story{Main: Code{opVar(0x08, large(0x1234)), opVar(0x08, small(5)), opVar(0x09, small(g0)), opVar(0x09, small(g1))}}, globals: map[byte]word{g0: 5, g1: 0x1234}},
+		"pop":            {story: Story{Main: Code{opVar(0x08, small(1)), opVar(0x08, small(2)), op0(0x09), opVar(0x09, small(g0))}}, globals: map[byte]word{g0: 1}},
+
+		// Memory
+		"loadw":  {story: Story{Arrays: map[string][]byte{"table": {0x12, 0x34, 0x56, 0x78}}, Main: Code{op2(0x0f, teststory.ArrayAddress("table"), small(1)).Store(g0)}}, globals: map[byte]word{g0: 0x5678}},
+		"loadb":  {story: Story{Arrays: map[string][]byte{"table": {0x12, 0x34, 0x56, 0x78}}, Main: Code{op2(0x10, teststory.ArrayAddress("table"), small(1)).Store(g0)}}, globals: map[byte]word{g0: 0x34}},
+		"storew": {story: Story{Arrays: map[string][]byte{"table": {0x12, 0x34, 0x56, 0x78}}, Main: Code{opVar(0x01, teststory.ArrayAddress("table"), small(1), large(0xabcd)), op2(0x0f, teststory.ArrayAddress("table"), small(1)).Store(g0)}}, globals: map[byte]word{g0: 0xabcd}},
+		"storeb": {story: Story{Arrays: map[string][]byte{"table": {0x12, 0x34, 0x56, 0x78}}, Main: Code{opVar(0x02, teststory.ArrayAddress("table"), small(3), small(0xef)), op2(0x10, teststory.ArrayAddress("table"), small(3)).Store(g0)}}, globals: map[byte]word{g0: 0xef}},
+
+		// Calls and returns
+		"call with arguments": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("double"), small(21)).Store(g0)},
+			Routines: map[string]Routine{"double": {Locals: []word{0}, Code: Code{op2(0x14, v(1), v(1)).Store(1), op1(0x0b, v(1))}}},
+		}, globals: map[byte]word{g0: 42}},
+		"call with initial locals": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("sum"), small(10)).Store(g0)},
+			Routines: map[string]Routine{"sum": {Locals: []word{5, 6}, Code: Code{op2(0x14, v(1), v(2)).Store(0), op0(0x08)}}},
+		}, globals: map[byte]word{g0: 16}},
+		"call V5 locals start at 0": {story: Story{
+			Version:  5,
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("second")).Store(g0)},
+			Routines: map[string]Routine{"second": {Locals: []word{0, 0}, Code: Code{op1(0x0b, v(2))}}},
+		}, globals: map[byte]word{g0: 0}},
+		"ret": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("answer")).Store(g0)},
+			Routines: map[string]Routine{"answer": {Code: Code{op1(0x0b, large(0x2a2a))}}},
+		}, globals: map[byte]word{g0: 0x2a2a}},
+		"rtrue": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("yes")).Store(g0)},
+			Routines: map[string]Routine{"yes": {Code: Code{op0(0x00)}}},
+		}, globals: map[byte]word{g0: 1}},
+		"rfalse": {story: Story{
+			Globals:  map[byte]word{g0: 5},
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("no")).Store(g0)},
+			Routines: map[string]Routine{"no": {Code: Code{op0(0x01)}}},
+		}, globals: map[byte]word{g0: 0}},
+		"ret_popped": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("popped")).Store(g0)},
+			Routines: map[string]Routine{"popped": {Code: Code{opVar(0x08, small(9)), op0(0x08)}}},
+		}, globals: map[byte]word{g0: 9}},
+		"print_ret": {story: Story{
+			Main:     Code{opVar(0x00, teststory.RoutineAddress("done")).Store(g0)},
+			Routines: map[string]Routine{"done": {Code: Code{op0(0x03).Text("Done")}}},
+		}, globals: map[byte]word{g0: 1}, output: "Done\n"},
+		"jump": {story: Story{
+			Globals: map[byte]word{g0: 5},
+			Main:    Code{op1(0x0c, teststory.Offset("end")), storeGlobal(g0, 2), teststory.Label("end")},
+		}, globals: map[byte]word{g0: 5}},
+
+		// Objects
+		"insert_obj": {story: Story{Objects: testObjects, Main: Code{op2(0x0e, small(4), small(1))}}, check: func(t *testing.T, zmachine *ZMachine) {
+			children, err := zmachine.Objects.Children(1)
+			testassert.NoError(t, err)
+			testassert.True(t, slices.Equal([]ObjectId{4, 2, 3}, children))
+		}},
+		"remove_obj": {story: Story{Objects: testObjects, Main: Code{op1(0x09, small(2)), op1(0x03, small(2)).Store(g0)}}, globals: map[byte]word{g0: 0}, check: func(t *testing.T, zmachine *ZMachine) {
+			children, err := zmachine.Objects.Children(1)
+			testassert.NoError(t, err)
+			testassert.True(t, slices.Equal([]ObjectId{3}, children))
+		}},
+		"get_parent":    {story: Story{Objects: testObjects, Main: Code{op1(0x03, small(3)).Store(g0)}}, globals: map[byte]word{g0: 1}},
+		"set_attr":      {story: Story{Objects: testObjects, Main: append(Code{op2(0x0b, small(4), small(7))}, branches(op2(0x0a, small(4), small(7)))...)}, globals: map[byte]word{g0: 1}},
+		"clear_attr":    {story: Story{Objects: testObjects, Main: append(Code{op2(0x0c, small(1), small(3))}, branches(op2(0x0a, small(1), small(3)))...)}, globals: map[byte]word{g0: 2}},
+		"get_prop word": {story: Story{Objects: testObjects, Main: Code{op2(0x11, small(1), small(10)).Store(g0)}}, globals: map[byte]word{g0: 0x1234}},
+		"get_prop byte": {story: Story{Objects: testObjects, Main: Code{op2(0x11, small(1), small(5)).Store(g0)}}, globals: map[byte]word{g0: 0x56}},
+		"get_prop long": {
+			story:   Story{Objects: testObjects, Main: Code{op2(0x11, small(1), small(20)).Store(g0)}},
+			globals: map[byte]word{g0: 0x0102},
+			errors:  []string{"Property 20 of Object 1 is 3 bytes long, only 1 and 2 byte properties can be read as a value"},
+		},
+		"get_prop_addr":         {story: Story{Objects: testObjects, Main: Code{op2(0x12, small(1), small(10)).Store(g1), op2(0x10, v(g1), small(1)).Store(g0)}}, globals: map[byte]word{g0: 0x34}},
+		"get_prop_addr missing": {story: Story{Objects: testObjects, Main: Code{op2(0x12, small(1), small(7)).Store(g0)}}, globals: map[byte]word{g0: 0}},
+		"get_prop_len":          {story: Story{Objects: testObjects, Main: Code{op2(0x12, small(1), small(20)).Store(g1), op1(0x04, v(g1)).Store(g0)}}, globals: map[byte]word{g0: 3}},
+		"get_prop_len V5 long":  {story: Story{Version: 5, Objects: []teststory.Object{{Properties: []teststory.Property{{Number: 40, Data: make([]byte, 64)}}}}, Main: Code{op2(0x12, small(1), small(40)).Store(g1), op1(0x04, v(g1)).Store(g0)}}, globals: map[byte]word{g0: 64}},
+		"get_next_prop":         {story: Story{Objects: testObjects, Main: Code{op2(0x13, small(1), small(0)).Store(g0), op2(0x13, small(1), small(20)).Store(g1), op2(0x13, small(1), small(5)).Store(g2)}}, globals: map[byte]word{g0: 20, g1: 10, g2: 0}},
+		"get_next_prop missing": {
+			story:   Story{Objects: testObjects, Globals: map[byte]word{g0: 9}, Main: Code{op2(0x13, small(1), small(7)).Store(g0)}},
+			globals: map[byte]word{g0: 0},
+			errors:  []string{"Object 1 has no Property 7 to follow"},
+		},
+		"get_prop out of range": {
+			story:   Story{Objects: testObjects, Globals: map[byte]word{g0: 9}, Main: Code{op2(0x11, small(1), small(40)).Store(g0)}},
+			globals: map[byte]word{g0: 0},
+			errors:  []string{"Property 40 of Object 1 is out of range, objects have 31 properties"},
+		},
+		"put_prop":  {story: Story{Objects: testObjects, Main: Code{opVar(0x03, small(1), small(10), large(0xabcd)), op2(0x11, small(1), small(10)).Store(g0)}}, globals: map[byte]word{g0: 0xabcd}},
+		"print_obj": {story: Story{Objects: testObjects, Main: Code{op1(0x0a, small(1))}}, output: "box"},
+
+		// Printing
+		"print":       {story: Story{Main: Code{op0(0x02).Text("Hello, world")}}, output: "Hello, world"},
+		"print_addr":  {story: Story{Strings: map[string]string{"hi": "hi there"}, Main: Code{op2(0x16, teststory.StringAddress("hi"), small(2)).Store(0), op1(0x07, v(0))}}, output: "hi there"},
+		"print_paddr": {story: Story{Strings: map[string]string{"greeting": "Hello"}, Main: Code{op1(0x0d, teststory.StringAddress("greeting"))}}, output: "Hello"},
+		"print_char":  {story: Story{Main: Code{opVar(0x05, small('A'))}}, output: "A"},
+		"print_num":   {story: Story{Main: Code{opVar(0x06, large(0xfffe))}}, output: "-2"},
+		"new_line":    {story: Story{Main: Code{opVar(0x05, small('a')), op0(0x0b), opVar(0x05, small('b'))}}, output: "a\nb"},
+
+		// Small negative numbers make the generator count up from 1
+		"random predictable": {story: Story{Main: Code{opVar(0x07, large(0xfffd)).Store(g0), opVar(0x07, small(10)).Store(g1), opVar(0x07, small(10)).Store(g2)}}, globals: map[byte]word{g0: 0, g1: 1, g2: 2}},
+
+		// Input
+		"read": {story: Story{Arrays: readArrays, Dictionary: readDictionary, Main: append(Code{
+			opVar(0x04, teststory.ArrayAddress("text"), teststory.ArrayAddress("parse")),
+			op2(0x10, teststory.ArrayAddress("parse"), small(1)).Store(g2),
+			op2(0x0f, teststory.ArrayAddress("parse"), small(1)).Store(g1),
+		}, branches(op2(0x01, v(g1), teststory.WordAddress("take")))...)}, input: []string{"take lamp"}, globals: map[byte]word{g0: 1, g2: 2}, output: "take lamp\n"},
+		"read V5": {story: Story{Version: 5, Arrays: readArrays, Dictionary: readDictionary, Main: Code{
+			opVar(0x04, teststory.ArrayAddress("text"), teststory.ArrayAddress("parse")).Store(g0),
+			op2(0x10, teststory.ArrayAddress("text"), small(1)).Store(g1),
+			op2(0x10, teststory.ArrayAddress("parse"), small(1)).Store(g2),
+		}}, input: []string{"lamp,take"}, globals: map[byte]word{g0: 13, g1: 9, g2: 3}, output: "lamp,take\n"},
+		"read_char": {story: Story{Version: 4, Main: Code{opVar(0x16, small(1)).Store(g0)}}, keys: "x", globals: map[byte]word{g0: 'x'}},
+
+		// Screen
+		"split_window": {story: Story{Main: Code{opVar(0x0a, small(2)), opVar(0x05, small('l'))}}, output: "l", screen: func(t *testing.T, sim tcell.SimulationScreen) {
+			testassert.Same(t, "", screenRow(sim, 0))
+			testassert.Same(t, "l", screenRow(sim, 24))
+		}},
+		"set_window": {story: Story{Main: Code{opVar(0x0a, small(2)), opVar(0x0b, small(1)), opVar(0x05, small('u')), opVar(0x0b, small(0)), opVar(0x05, small('l'))}}, output: "l", screen: func(t *testing.T, sim tcell.SimulationScreen) {
+			testassert.Same(t, "u", screenRow(sim, 0))
+			testassert.Same(t, "", screenRow(sim, 1))
+			testassert.Same(t, "l", screenRow(sim, 24))
+		}},
+		"buffer_mode": {story: Story{Version: 4, Main: Code{opVar(0x12, small(0)), op0(0x02).Text("unbuffered")}}, output: "unbuffered", check: func(t *testing.T, zmachine *ZMachine) { testassert.False(t, zmachine.Screen.Wordwrap) }},
+		"set_text_style": {story: Story{Version: 4, Main: Code{op0(0x02).Text("a"), opVar(0x11, small(2)), op0(0x02).Text("bold")}}, output: "abold", screen: func(t *testing.T, sim tcell.SimulationScreen) {
+			testassert.Same(t, "abold", screenRow(sim, 24))
+			checkCell(t, sim, 0, 24, tcell.ColorWhite, tcell.ColorBlack, tcell.AttrNone)
+			checkCell(t, sim, 1, 24, tcell.ColorWhite, tcell.ColorBlack, tcell.AttrBold)
+		}},
+		"set_colour": {story: Story{Version: 5, Main: Code{op2(0x1b, small(3), small(6)), op0(0x02).Text("red")}}, output: "red", screen: func(t *testing.T, sim tcell.SimulationScreen) {
+			checkCell(t, sim, 0, 24, tcell.ColorMaroon, tcell.ColorNavy, tcell.AttrNone)
+			checkCell(t, sim, 3, 24, tcell.ColorWhite, tcell.ColorBlack, tcell.AttrNone)
+		}},
+		"set_true_colour": {story: Story{Version: 5, Main: Code{opExt(0x0d, large(0x001f), large(0x7c00)), op0(0x02).Text("red")}}, output: "red", screen: func(t *testing.T, sim tcell.SimulationScreen) {
+			checkCell(t, sim, 0, 24, tcell.NewRGBColor(255, 0, 0), tcell.NewRGBColor(0, 0, 255), tcell.AttrNone)
+		}},
+
+		// Sound, and the interpreter itself
+		"sound_effect": {story: Story{Main: Code{opVar(0x15, small(1)), opVar(0x15, small(3), small(2), small(5)), opVar(0x15, small(3), small(3))}}, check: func(t *testing.T, zmachine *ZMachine) {
+			calls := zmachine.Sound.(*recordingSoundPlayer).calls
+			testassert.Same(t, "beep true|start 3 at 5|stop 3", strings.Join(calls, "|"))
+		}},
+		"verify":              {story: Story{Main: branches(op0(0x0d))}, globals: map[byte]word{g0: 1}},
+		"verify bad checksum": {story: Story{Main: branches(op0(0x0d)), BadChecksum: true}, globals: map[byte]word{g0: 2}},
+		"quit":                {story: Story{Main: Code{op0(0x02).Text("bye"), op0(0x0a), op0(0x02).Text("never")}}, output: "bye"},
+	}
+}
+
+func TestOpcodes(t *testing.T) {
+	for name, s := range opcodeTests() {
+		t.Run(name, func(t *testing.T) {
+			zmachine, output, reported, shown := runStory(t, s.story, s.input, s.keys)
+
+			for variable, expected := range s.globals {
+				actual, err := zmachine.getVariable(VarNum(variable)).Read()
+				testassert.NoError(t, err)
+				if expected != actual {
+					t.Errorf("Expected global %02x to be %04x, Received %04x", variable, expected, actual)
+				}
+			}
+			testassert.Same(t, s.output, output)
+			testassert.Same(t, strings.Join(s.errors, "\n"), strings.Join(reported, "\n"))
+
+			if s.check != nil {
+				s.check(t, zmachine)
+			}
+			if s.screen != nil {
+				s.screen(t, shown)
+			}
+		})
+	}
+}
+
+func TestOpcodes_CoverEveryHandler(t *testing.T) {
+	tested := map[string]bool{}
+	for name := range opcodeTests() {
+		tested[strings.Fields(name)[0]] = true
+	}
+
+	for _, name := range handlerNames {
+		if !tested[name] {
+			t.Errorf("No opcode test for %s", name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package zmachine
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Field numbers from pprof's profile.proto. The profile is written by hand, since protocol
+// buffers are simple to encode and this is the only place they're needed.
+const (
+	pprofProfileSampleType    = 1
+	pprofProfileSample        = 2
+	pprofProfileLocation      = 4
+	pprofProfileFunction      = 5
+	pprofProfileStringTable   = 6
+	pprofProfileTimeNanos     = 9
+	pprofProfileDurationNanos = 10
+	pprofProfilePeriodType    = 11
+	pprofProfilePeriod        = 12
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationID = 1
+	pprofSampleValue      = 2
+
+	pprofLocationID      = 1
+	pprofLocationAddress = 3
+	pprofLocationLine    = 4
+
+	pprofLineFunctionID = 1
+
+	pprofFunctionID         = 1
+	pprofFunctionName       = 2
+	pprofFunctionSystemName = 3
+	pprofFunctionFilename   = 4
+)
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// protoMessage builds an encoded protocol buffer message
+type protoMessage []byte
+
+func (m protoMessage) varint(field int, value uint64) protoMessage {
+	m = binary.AppendUvarint(m, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(m, value)
+}
+
+func (m protoMessage) bytes(field int, data []byte) protoMessage {
+	m = binary.AppendUvarint(m, uint64(field<<3|wireBytes))
+	m = binary.AppendUvarint(m, uint64(len(data)))
+	return append(m, data...)
+}
+
+func (m protoMessage) packed(field int, values []uint64) protoMessage {
+	data := make([]byte, 0, len(values))
+	for _, value := range values {
+		data = binary.AppendUvarint(data, value)
+	}
+	return m.bytes(field, data)
+}
+
+// stringTable numbers the strings in a profile, where the first must be empty
+type stringTable struct {
+	strings []string
+	index   map[string]int
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, index: map[string]int{"": 0}}
+}
+
+func (table *stringTable) add(s string) uint64 {
+	i, ok := table.index[s]
+	if !ok {
+		i = len(table.strings)
+		table.strings = append(table.strings, s)
+		table.index[s] = i
+	}
+	return uint64(i)
+}
+
+// writePprof writes a gzipped pprof profile with a sample for each distinct chain of calls.
+// Each routine is a function with a single location at its address.
+func (profiler *Profiler) writePprof(w io.Writer, now time.Time) error {
+	strings := newStringTable()
+	profile := protoMessage{}
+
+	valueType := func(kind string, unit string) []byte {
+		return protoMessage{}.varint(pprofValueTypeType, strings.add(kind)).varint(pprofValueTypeUnit, strings.add(unit))
+	}
+	profile = profile.bytes(pprofProfileSampleType, valueType("instructions", "count"))
+	profile = profile.bytes(pprofProfileSampleType, valueType("wall", "nanoseconds"))
+
+	filename := strings.add(profiler.memory.GetPath())
+	ids := map[word]uint64{}
+	for _, routine := range profiler.Routines() {
+		id := uint64(len(ids) + 1)
+		ids[routine.Packed] = id
+		name := strings.add(routine.Name)
+
+		function := protoMessage{}.varint(pprofFunctionID, id).varint(pprofFunctionName, name).
+			varint(pprofFunctionSystemName, name).varint(pprofFunctionFilename, filename)
+		profile = profile.bytes(pprofProfileFunction, function)
+
+		line := protoMessage{}.varint(pprofLineFunctionID, id)
+		location := protoMessage{}.varint(pprofLocationID, id).varint(pprofLocationAddress, uint64(routine.Address)).
+			bytes(pprofLocationLine, line)
+		profile = profile.bytes(pprofProfileLocation, location)
+	}
+
+	var addSamples func(node *callNode)
+	addSamples = func(node *callNode) {
+		if node.instructions > 0 || node.time > 0 {
+			// Locations run from the innermost call outwards
+			locations := make([]uint64, 0)
+			for n := node; n != &profiler.root; n = n.parent {
+				locations = append(locations, ids[n.packed])
+			}
+			sample := protoMessage{}.packed(pprofSampleLocationID, locations).
+				packed(pprofSampleValue, []uint64{uint64(node.instructions), uint64(node.time.Nanoseconds())})
+			profile = profile.bytes(pprofProfileSample, sample)
+		}
+		for _, child := range node.children {
+			addSamples(child)
+		}
+	}
+	addSamples(&profiler.root)
+
+	profile = profile.varint(pprofProfileTimeNanos, uint64(profiler.started.UnixNano()))
+	profile = profile.varint(pprofProfileDurationNanos, uint64(now.Sub(profiler.started).Nanoseconds()))
+	profile = profile.bytes(pprofProfilePeriodType, valueType("instructions", "count"))
+	profile = profile.varint(pprofProfilePeriod, 1)
+
+	// The string table goes last, once every string has been added
+	for _, s := range strings.strings {
+		profile = profile.bytes(pprofProfileStringTable, []byte(s))
+	}
+
+	compressed := gzip.NewWriter(w)
+	_, err := compressed.Write(profile)
+	if err != nil {
+		return err
+	}
+	return compressed.Close()
+}
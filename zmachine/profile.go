@@ -0,0 +1,248 @@
+package zmachine
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+type ProfileFormat int
+
+const (
+	PF_Text  ProfileFormat = 0 // A table of routines, busiest first
+	PF_Pprof ProfileFormat = 1 // A gzipped pprof profile, for go tool pprof
+)
+
+// RoutineProfile totals the work done by one routine across all of its calls. Inclusive totals
+// add the routines it called, and count recursive calls only once.
+type RoutineProfile struct {
+	Packed                word // Packed address used to call the routine, or 0 for the main routine
+	Address               memory.Address
+	Name                  string
+	Calls                 int
+	Instructions          int
+	InclusiveInstructions int
+	Time                  time.Duration
+	InclusiveTime         time.Duration
+}
+
+// callNode is a routine reached by a particular chain of calls, which becomes a pprof sample
+type callNode struct {
+	packed       word
+	parent       *callNode
+	children     map[word]*callNode
+	instructions int
+	time         time.Duration
+}
+
+// profileCall is a routine call the profiler is following, one for each frame on the stack
+type profileCall struct {
+	routine      memory.Address
+	packed       word
+	node         *callNode
+	instructions int // Total instructions executed when the call started
+	start        time.Time
+	waited       time.Duration // Total time spent waiting for input when the call started
+}
+
+// Profiler counts the instructions executed and the wall time spent in each routine
+type Profiler struct {
+	Names        func(packed word) string // Names routines in the report, when debug information is available
+	output       io.Writer
+	format       ProfileFormat
+	memory       *memory.Memory
+	now          func() time.Time
+	started      time.Time
+	last         time.Time
+	root         callNode
+	calls        []profileCall
+	routines     map[word]*RoutineProfile
+	active       map[word]int // Calls to each routine on the stack, so recursion is only counted once
+	instructions int
+	waited       time.Duration // Time spent waiting for input, which isn't charged to any routine
+}
+
+func NewProfiler(m *memory.Memory, output io.Writer, format ProfileFormat) *Profiler {
+	return newProfiler(m, output, format, time.Now)
+}
+
+func newProfiler(m *memory.Memory, output io.Writer, format ProfileFormat, now func() time.Time) *Profiler {
+	started := now()
+	return &Profiler{
+		output:   output,
+		format:   format,
+		memory:   m,
+		now:      now,
+		started:  started,
+		last:     started,
+		root:     callNode{children: map[word]*callNode{}},
+		routines: map[word]*RoutineProfile{},
+		active:   map[word]int{},
+	}
+}
+
+// instruction counts an instruction about to run in the top frame. The time since the last
+// instruction goes to the routine that ran it.
+func (profiler *Profiler) instruction(frames *CallStack) {
+	if profiler == nil {
+		return
+	}
+
+	now := profiler.now()
+	profiler.charge(now)
+	profiler.follow(frames, now)
+
+	top := profiler.calls[len(profiler.calls)-1]
+	top.node.instructions++
+	profiler.routines[top.packed].Instructions++
+	profiler.instructions++
+}
+
+// wait stops the clock while the player is being waited on, and restarts it once they answer
+func (profiler *Profiler) wait(waiting bool) {
+	if profiler == nil {
+		return
+	}
+
+	now := profiler.now()
+	if waiting {
+		profiler.charge(now)
+		return
+	}
+	profiler.waited += now.Sub(profiler.last)
+	profiler.last = now
+}
+
+func (profiler *Profiler) charge(now time.Time) {
+	if len(profiler.calls) > 0 {
+		top := profiler.calls[len(profiler.calls)-1]
+		elapsed := now.Sub(profiler.last)
+		top.node.time += elapsed
+		profiler.routines[top.packed].Time += elapsed
+	}
+	profiler.last = now
+}
+
+// follow matches the calls to the frames on the stack. Calls and returns show up as frames
+// being added or removed, while a restore can replace the stack completely.
+func (profiler *Profiler) follow(frames *CallStack, now time.Time) {
+	for n := len(profiler.calls); n > 0 && (n > frames.Size() || profiler.calls[n-1].routine != frames.Frame(n-1).Routine); n-- {
+		profiler.leave(now)
+	}
+
+	for len(profiler.calls) < frames.Size() {
+		profiler.enter(frames.Frame(len(profiler.calls)).Routine, now)
+	}
+}
+
+func (profiler *Profiler) enter(routine memory.Address, now time.Time) {
+	packed := word(0)
+	if routine != 0 {
+		packed = profiler.memory.PackRoutineAddress(routine)
+	}
+
+	parent := &profiler.root
+	if len(profiler.calls) > 0 {
+		parent = profiler.calls[len(profiler.calls)-1].node
+	}
+	node, ok := parent.children[packed]
+	if !ok {
+		node = &callNode{packed: packed, parent: parent, children: map[word]*callNode{}}
+		parent.children[packed] = node
+	}
+
+	profile, ok := profiler.routines[packed]
+	if !ok {
+		profile = &RoutineProfile{Packed: packed, Address: routine}
+		profiler.routines[packed] = profile
+	}
+	profile.Calls++
+	profiler.active[packed]++
+
+	profiler.calls = append(profiler.calls, profileCall{routine, packed, node, profiler.instructions, now, profiler.waited})
+}
+
+func (profiler *Profiler) leave(now time.Time) {
+	call := profiler.calls[len(profiler.calls)-1]
+	profiler.calls = profiler.calls[:len(profiler.calls)-1]
+
+	profiler.active[call.packed]--
+	if profiler.active[call.packed] == 0 {
+		profile := profiler.routines[call.packed]
+		profile.InclusiveInstructions += profiler.instructions - call.instructions
+		profile.InclusiveTime += now.Sub(call.start) - (profiler.waited - call.waited)
+	}
+}
+
+// Routines lists the totals for every routine that was called, busiest first. Routines still
+// running only have their inclusive totals once the profiler is closed.
+func (profiler *Profiler) Routines() []RoutineProfile {
+	routines := make([]RoutineProfile, 0, len(profiler.routines))
+	for _, profile := range profiler.routines {
+		routine := *profile
+		routine.Name = profiler.name(routine.Packed)
+		routines = append(routines, routine)
+	}
+
+	slices.SortFunc(routines, func(a RoutineProfile, b RoutineProfile) int {
+		return cmp.Or(cmp.Compare(b.Instructions, a.Instructions), cmp.Compare(a.Packed, b.Packed))
+	})
+	return routines
+}
+
+func (profiler *Profiler) name(packed word) string {
+	if profiler.Names != nil {
+		if name := profiler.Names(packed); name != "" {
+			return name
+		}
+	}
+	if packed == 0 {
+		return "(main)"
+	}
+	return fmt.Sprintf("routine_%04x", packed)
+}
+
+// Close finishes every call still running, then writes the profile and closes the output if
+// it can be closed
+func (profiler *Profiler) Close() error {
+	now := profiler.now()
+	profiler.charge(now)
+	for len(profiler.calls) > 0 {
+		profiler.leave(now)
+	}
+
+	var err error
+	switch profiler.format {
+	case PF_Pprof:
+		err = profiler.writePprof(profiler.output, now)
+	default:
+		err = profiler.writeText(profiler.output, now)
+	}
+
+	if closer, ok := profiler.output.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+func (profiler *Profiler) writeText(w io.Writer, now time.Time) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(table, "Routine\tPacked\tCalls\tInstructions\tInclusive\tTime\tInclusive\t\n")
+	for _, routine := range profiler.Routines() {
+		fmt.Fprintf(table, "%s\t%04x\t%d\t%d\t%d\t%s\t%s\t\n", routine.Name, routine.Packed, routine.Calls, routine.Instructions,
+			routine.InclusiveInstructions, routine.Time.Round(time.Microsecond), routine.InclusiveTime.Round(time.Microsecond))
+	}
+	err := table.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "\n%d instructions in %s\n", profiler.instructions, now.Sub(profiler.started).Round(time.Millisecond))
+	return err
+}
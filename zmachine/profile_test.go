@@ -0,0 +1,122 @@
+package zmachine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func TestProfiler_CountsRoutines(t *testing.T) {
+	m, err := memory.NewMemoryFromFile("./blank.z3", func(m *memory.Memory) {})
+	testassert.NoError(t, err)
+
+	// Every reading of the clock is a millisecond after the one before
+	clock := time.Unix(0, 0)
+	now := func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	output := bytes.Buffer{}
+	profiler := newProfiler(m, &output, PF_Text, now)
+
+	frames := NewCallStack()
+	frames.Push(Frame{}, 0)
+	run := func(count int) {
+		for range count {
+			profiler.instruction(&frames)
+		}
+	}
+
+	run(2)
+	frames.Push(Frame{Routine: 0x400}, 0)
+	run(3)
+	frames.Push(Frame{Routine: 0x500}, 0)
+	run(1)
+	frames.Pop()
+	run(1)
+	frames.Push(Frame{Routine: 0x500}, 0)
+	run(2)
+	frames.Pop()
+	frames.Pop()
+	run(1)
+	testassert.NoError(t, profiler.Close())
+
+	routines := map[word]RoutineProfile{}
+	for _, routine := range profiler.Routines() {
+		routines[routine.Packed] = routine
+	}
+	testassert.Same(t, 3, len(routines))
+
+	main, a, b := routines[0], routines[0x200], routines[0x280]
+	testassert.Same(t, "(main)", main.Name)
+	testassert.Same(t, 3, main.Instructions)
+	testassert.Same(t, 10, main.InclusiveInstructions)
+	testassert.Same(t, 1, a.Calls)
+	testassert.Same(t, 4, a.Instructions)
+	testassert.Same(t, 7, a.InclusiveInstructions)
+	testassert.Same(t, 2, b.Calls)
+	testassert.Same(t, 3, b.Instructions)
+	testassert.Same(t, 3, b.InclusiveInstructions)
+	testassert.Same(t, 3*time.Millisecond, b.Time)
+	testassert.Same(t, "routine_0280", b.Name)
+	testassert.True(t, bytes.Contains(output.Bytes(), []byte("routine_0200")))
+}
+
+func TestProfiler_LeavesOutWaitingForInput(t *testing.T) {
+	m, err := memory.NewMemoryFromFile("./blank.z3", func(m *memory.Memory) {})
+	testassert.NoError(t, err)
+
+	clock := time.Unix(0, 0)
+	profiler := newProfiler(m, &bytes.Buffer{}, PF_Text, func() time.Time { return clock })
+
+	frames := NewCallStack()
+	frames.Push(Frame{}, 0)
+	frames.Push(Frame{Routine: 0x400}, 0)
+	profiler.instruction(&frames)
+	clock = clock.Add(time.Millisecond)
+	profiler.wait(true)
+	clock = clock.Add(time.Minute)
+	profiler.wait(false)
+	clock = clock.Add(time.Millisecond)
+	testassert.NoError(t, profiler.Close())
+
+	// Only the two milliseconds either side of the wait were spent running the story
+	routines := profiler.Routines()
+	testassert.Same(t, 2, len(routines))
+	for _, routine := range routines {
+		testassert.Same(t, 2*time.Millisecond, routine.InclusiveTime)
+	}
+	testassert.Same(t, 2*time.Millisecond, profiler.routines[0x200].Time)
+}
+
+func TestProfiler_WritesPprof(t *testing.T) {
+	m, err := memory.NewMemoryFromFile("./blank.z3", func(m *memory.Memory) {})
+	testassert.NoError(t, err)
+
+	output := bytes.Buffer{}
+	profiler := NewProfiler(m, &output, PF_Pprof)
+	profiler.Names = func(packed word) string {
+		if packed == 0x200 {
+			return "Main__"
+		}
+		return ""
+	}
+
+	frames := NewCallStack()
+	frames.Push(Frame{}, 0)
+	frames.Push(Frame{Routine: 0x400}, 0)
+	profiler.instruction(&frames)
+	testassert.NoError(t, profiler.Close())
+
+	reader, err := gzip.NewReader(&output)
+	testassert.NoError(t, err)
+	profile, err := io.ReadAll(reader)
+	testassert.NoError(t, err)
+	testassert.True(t, bytes.Contains(profile, []byte("Main__")))
+	testassert.True(t, bytes.Contains(profile, []byte("instructions")))
+}
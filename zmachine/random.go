@@ -0,0 +1,86 @@
+package zmachine
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+type RandomMode int
+
+const (
+	RM_Random      RandomMode = iota // Seeded from the seed source, normally the clock
+	RM_Seeded                        // Seeded by the story, so the sequence repeats
+	RM_Predictable                   // Counts 1, 2, ... up to a limit and starts again
+)
+
+// Seeds below this put the generator into predictable mode rather than seeding it, as the
+// spec suggests so stories can be tested with known values
+const predictableLimit = 1000
+
+// RNG produces the numbers for the random opcode
+type RNG struct {
+	mode   RandomMode
+	source *rand.Rand
+	limit  int
+	count  int
+	seed   func() uint64
+}
+
+func ClockSeed() uint64 {
+	return uint64(time.Now().UnixNano())
+}
+
+// NewRNG starts a generator in random mode. The seed function provides the seed whenever random
+// mode is entered, so a fixed seed makes even "truly" random numbers repeatable.
+func NewRNG(seed func() uint64) *RNG {
+	rng := &RNG{seed: seed}
+	rng.Randomize()
+	return rng
+}
+
+func (rng *RNG) Mode() RandomMode {
+	return rng.mode
+}
+
+func (rng *RNG) Randomize() {
+	rng.mode = RM_Random
+	rng.reseed(rng.seed())
+}
+
+func (rng *RNG) Seed(seed uint64) {
+	rng.mode = RM_Seeded
+	rng.reseed(seed)
+}
+
+func (rng *RNG) Predictable(limit int) {
+	rng.mode = RM_Predictable
+	rng.limit = max(limit, 1)
+	rng.count = 0
+}
+
+func (rng *RNG) reseed(seed uint64) {
+	rng.source = rand.New(rand.NewPCG(seed, seed))
+}
+
+// Next returns a number from 1 to n inclusive
+func (rng *RNG) Next(n int) int {
+	if rng.mode == RM_Predictable {
+		rng.count = rng.count%rng.limit + 1
+		return (rng.count-1)%n + 1
+	}
+
+	return rng.source.IntN(n) + 1
+}
+
+// Reseed carries out the random opcode's reseeding, where 0 returns to random mode, small values
+// select predictable mode and any other value seeds the generator
+func (rng *RNG) Reseed(value int) {
+	switch {
+	case value == 0:
+		rng.Randomize()
+	case value < predictableLimit:
+		rng.Predictable(value)
+	default:
+		rng.Seed(uint64(value))
+	}
+}
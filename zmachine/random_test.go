@@ -0,0 +1,64 @@
+package zmachine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func fixedSeed(seed uint64) func() uint64 {
+	return func() uint64 { return seed }
+}
+
+func sequence(rng *RNG, n int, count int) []int {
+	values := make([]int, 0, count)
+	for range count {
+		values = append(values, rng.Next(n))
+	}
+	return values
+}
+
+func TestRNG_Predictable(t *testing.T) {
+	rng := NewRNG(fixedSeed(1))
+	rng.Reseed(3)
+
+	testassert.Same(t, RM_Predictable, rng.Mode())
+	testassert.Same(t, "[1 2 3 1 2 3 1]", fmt.Sprint(sequence(rng, 10, 7)))
+}
+
+func TestRNG_PredictableStaysInRange(t *testing.T) {
+	rng := NewRNG(fixedSeed(1))
+	rng.Reseed(5)
+
+	testassert.Same(t, "[1 2 1 2 1]", fmt.Sprint(sequence(rng, 2, 5)))
+}
+
+func TestRNG_SeededRepeats(t *testing.T) {
+	rng := NewRNG(fixedSeed(1))
+	rng.Reseed(12345)
+	testassert.Same(t, RM_Seeded, rng.Mode())
+	first := sequence(rng, 100, 20)
+
+	rng.Reseed(12345)
+	testassert.Same(t, fmt.Sprint(first), fmt.Sprint(sequence(rng, 100, 20)))
+}
+
+func TestRNG_FixedSeedMakesRandomModeRepeatable(t *testing.T) {
+	a := NewRNG(fixedSeed(42))
+	b := NewRNG(fixedSeed(42))
+	testassert.Same(t, RM_Random, a.Mode())
+	testassert.Same(t, fmt.Sprint(sequence(a, 100, 20)), fmt.Sprint(sequence(b, 100, 20)))
+
+	// Returning to random mode starts again from the fixed seed
+	a.Reseed(0)
+	b.Reseed(0)
+	testassert.Same(t, fmt.Sprint(sequence(a, 6, 20)), fmt.Sprint(sequence(b, 6, 20)))
+}
+
+func TestRNG_NextInRange(t *testing.T) {
+	rng := NewRNG(ClockSeed)
+	for _, value := range sequence(rng, 6, 1000) {
+		testassert.True(t, 1 <= value && value <= 6)
+	}
+}
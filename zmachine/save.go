@@ -0,0 +1,164 @@
+package zmachine
+
+import (
+	"errors"
+	"os"
+	"slices"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/quetzal"
+	"github.com/Drakmyth/golang-zmachine/zstring"
+)
+
+// snapshot captures the whole game state. The saved PC is that of the current frame, so when
+// taken during an instruction, restoring it runs that instruction again. That suits /undo, but
+// save files use fileSnapshot.
+func (zmachine *ZMachine) snapshot() quetzal.Save {
+	return zmachine.snapshotOf(&zmachine.Stack, zmachine.Memory.GetDynamicMemory())
+}
+
+// snapshotOf captures the given frames and dynamic memory, which may be copies taken earlier
+func (zmachine *ZMachine) snapshotOf(frames *CallStack, dynamic []byte) quetzal.Save {
+	m := zmachine.Memory
+	top, err := frames.Peek()
+	if err != nil {
+		top = &Frame{}
+	}
+
+	save := quetzal.Save{
+		Header: quetzal.Header{
+			Release:  m.GetReleaseNumber(),
+			Serial:   m.GetSerialCode(),
+			Checksum: m.GetChecksum(),
+			PC:       uint32(top.Counter),
+		},
+		Memory: dynamic,
+		Frames: make([]quetzal.Frame, 0, frames.Size()),
+	}
+
+	for i := range frames.Size() {
+		frame := frames.Frame(i)
+		f := quetzal.Frame{
+			ArgCount: frame.ArgCount,
+			Locals:   slices.Clone(frames.Locals(i)),
+			Stack:    slices.Clone(frames.Values(i)),
+		}
+
+		// Quetzal keeps the caller's counter with the frame being returned from
+		if i > 0 {
+			f.ReturnPC = uint32(frames.Frame(i - 1).Counter)
+			f.DiscardResult = frame.DiscardReturn
+			f.ResultVariable = uint8(frame.ReturnVariable.Number)
+		}
+		save.Frames = append(save.Frames, f)
+	}
+
+	return save
+}
+
+// restoreSnapshot replaces the game state with a saved one, which must be from the same story
+func (zmachine *ZMachine) restoreSnapshot(save quetzal.Save) error {
+	m := zmachine.Memory
+	h := save.Header
+	if h.Release != m.GetReleaseNumber() || h.Serial != m.GetSerialCode() || h.Checksum != m.GetChecksum() {
+		return errors.New("Save is from a different story")
+	}
+	if len(save.Frames) == 0 {
+		return errors.New("Save has no stack frames")
+	}
+	if len(save.Memory) != int(m.GetStaticMemoryAddress()) {
+		return errors.New("Save's memory is not the same length as dynamic memory")
+	}
+
+	// Build the whole stack before changing anything, so a bad save leaves the game as it was
+	stack := NewCallStack()
+	for i, f := range save.Frames {
+		frame := Frame{
+			Counter:        memory.Address(h.PC),
+			ArgCount:       f.ArgCount,
+			DiscardReturn:  f.DiscardResult,
+			ReturnVariable: zmachine.getVariable(VarNum(f.ResultVariable)),
+		}
+		if i+1 < len(save.Frames) {
+			frame.Counter = memory.Address(save.Frames[i+1].ReturnPC)
+		}
+
+		locals, err := stack.Push(frame, len(f.Locals))
+		if err != nil {
+			return err
+		}
+		copy(locals, f.Locals)
+		for _, value := range f.Stack {
+			err = stack.PushValue(value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Transcripting and fixed pitch are the player's settings rather than part of the game
+	settings := m.ReadWord(memory.Addr_RAM_W_Flags2) & word(memory.Flags2_TranscriptingOn|memory.Flags2_ForceFixedPitchPrinting)
+
+	err := m.SetDynamicMemory(save.Memory)
+	if err != nil {
+		return err
+	}
+	// Copied in place, since the instruction doing the restore holds on to the top frame
+	stack.CopyTo(&zmachine.Stack)
+
+	flags2 := m.ReadWord(memory.Addr_RAM_W_Flags2) &^ word(memory.Flags2_TranscriptingOn|memory.Flags2_ForceFixedPitchPrinting)
+	m.WriteWord(memory.Addr_RAM_W_Flags2, flags2|settings)
+	zmachine.advertiseCapabilities()
+
+	return nil
+}
+
+// fileSnapshot captures the game for a save file. Quetzal saves resume after the instruction
+// that made them, so a save made from a read is of the game once the read took an empty line,
+// which the story treats as no command at all, here or in any other interpreter.
+func (zmachine *ZMachine) fileSnapshot() (quetzal.Save, error) {
+	reading, prompt := zmachine.reading, zmachine.prompt
+	if reading == nil || prompt == nil {
+		return zmachine.snapshot(), nil
+	}
+
+	frame, err := zmachine.Stack.Peek()
+	if err != nil {
+		return quetzal.Save{}, err
+	}
+
+	// The state at the prompt is from the start of the read, so it puts the game back afterwards
+	err = zmachine.storeInput(*reading, []zstring.ZSCII{}, zstring.ZSCII_NewLine)
+	frame.Counter = reading.NextAddress
+	save := zmachine.snapshot()
+	return save, errors.Join(err, zmachine.restoreSnapshot(*prompt))
+}
+
+func (zmachine *ZMachine) saveFile(path string) error {
+	save, err := zmachine.fileSnapshot()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return save.Write(file, zmachine.Memory.GetOriginalDynamicMemory())
+}
+
+func (zmachine *ZMachine) restoreFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	save, err := quetzal.Read(file, zmachine.Memory.GetOriginalDynamicMemory())
+	if err != nil {
+		return err
+	}
+	return zmachine.restoreSnapshot(save)
+}
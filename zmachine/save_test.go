@@ -0,0 +1,119 @@
+package zmachine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/quetzal"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+	"github.com/Drakmyth/golang-zmachine/teststory"
+)
+
+// newSaveTestMachine builds just enough of a machine to save and restore, without a screen
+func newSaveTestMachine(t *testing.T) *ZMachine {
+	t.Helper()
+
+	m, err := memory.NewMemoryFromFile("./blank.z3", func(m *memory.Memory) {})
+	testassert.NoError(t, err)
+
+	zmachine := &ZMachine{Memory: m, Stack: NewCallStack()}
+	_, err = zmachine.Stack.Push(Frame{Counter: 0x4f05}, 0)
+	testassert.NoError(t, err)
+	testassert.NoError(t, zmachine.Stack.PushValue(7))
+	locals, err := zmachine.Stack.Push(Frame{
+		Counter:        0x5001,
+		ArgCount:       2,
+		ReturnVariable: zmachine.getVariable(0x10),
+	}, 3)
+	testassert.NoError(t, err)
+	copy(locals, []word{1, 2, 3})
+	return zmachine
+}
+
+func TestSnapshot_RestoresMemoryAndStack(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	address := memory.Address(0x100)
+	zmachine.Memory.WriteWord(address, 0xbeef)
+
+	save := zmachine.snapshot()
+
+	zmachine.Memory.WriteWord(address, 0x1234)
+	zmachine.Stack.Pop()
+
+	testassert.NoError(t, zmachine.restoreSnapshot(save))
+	testassert.Same(t, 0xbeef, zmachine.Memory.ReadWord(address))
+	testassert.Same(t, 2, zmachine.Stack.Size())
+
+	caller, callee := zmachine.Stack.Frame(0), zmachine.Stack.Frame(1)
+	testassert.Same(t, memory.Address(0x4f05), caller.Counter)
+	testassert.Same(t, 1, len(zmachine.Stack.Values(0)))
+	testassert.Same(t, memory.Address(0x5001), callee.Counter)
+	testassert.Same(t, 3, len(zmachine.Stack.Locals(1)))
+	testassert.Same(t, 2, callee.ArgCount)
+	testassert.Same(t, VarNum(0x10), callee.ReturnVariable.Number)
+}
+
+func TestSaveFile_RoundTrip(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	path := filepath.Join(t.TempDir(), "test.qzl")
+	address := memory.Address(0x100)
+
+	zmachine.Memory.WriteWord(address, 0xbeef)
+	testassert.NoError(t, zmachine.saveFile(path))
+
+	zmachine.Memory.WriteWord(address, 0x1234)
+	testassert.NoError(t, zmachine.restoreFile(path))
+	testassert.Same(t, 0xbeef, zmachine.Memory.ReadWord(address))
+}
+
+func TestRestoreSnapshot_RejectsOtherStories(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	save := zmachine.snapshot()
+	save.Header.Release++
+
+	testassert.ErrorMessage(t, "Save is from a different story", zmachine.restoreSnapshot(save))
+}
+
+func TestRestoreSnapshot_LeavesGameAloneWhenSaveIsBad(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	address := memory.Address(0x100)
+	save := zmachine.snapshot()
+	save.Memory[address] = 0xff
+	save.Frames[1].Stack = make([]word, StackWords+1)
+
+	testassert.True(t, zmachine.restoreSnapshot(save) != nil)
+	testassert.Same(t, 0, zmachine.Memory.ReadByte(address))
+	testassert.Same(t, 2, zmachine.Stack.Size())
+	testassert.Same(t, 1, len(zmachine.Stack.Values(0)))
+}
+
+func TestSaveFile_ResumesAfterRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.qzl")
+	text := make([]byte, 202)
+	text[0] = 200
+	story := teststory.Story{Version: 5, Arrays: map[string][]byte{"text": text}, Main: []teststory.Instruction{
+		teststory.OpVar(0x04, teststory.ArrayAddress("text"), teststory.Small(0)).Store(g0),
+	}}
+	zmachine, _, _, _ := runStory(t, story, []string{"/save " + path, "north"}, "")
+
+	file, err := os.Open(path)
+	testassert.NoError(t, err)
+	defer file.Close()
+	save, err := quetzal.Read(file, zmachine.Memory.GetOriginalDynamicMemory())
+	testassert.NoError(t, err)
+
+	// read with a large operand, a small one and a store takes six bytes
+	end := zmachine.Memory.GetInitialProgramCounter().OffsetBytes(6)
+	testassert.Same(t, uint32(end), save.Header.PC)
+
+	// The save has the read finished with an empty line, not the input typed afterwards
+	testassert.NoError(t, zmachine.restoreSnapshot(save))
+	frame, err := zmachine.Stack.Peek()
+	testassert.NoError(t, err)
+	testassert.Same(t, end, frame.Counter)
+	terminator, err := zmachine.getVariable(VarNum(g0)).Read()
+	testassert.NoError(t, err)
+	testassert.Same(t, 13, terminator)
+}
@@ -0,0 +1,83 @@
+package zmachine
+
+import (
+	"github.com/Drakmyth/golang-zmachine/sound"
+)
+
+// SoundPlayer carries out the sound_effect opcode. Volumes run from 1 to sound.MaxVolume, and
+// repeats are a count, sound.RepeatDefault or sound.RepeatForever. Start calls done once the
+// sound has finished, which may be from another goroutine.
+type SoundPlayer interface {
+	// Sampled reports whether sounds other than the two beeps can be played
+	Sampled() bool
+	Beep(high bool)
+	Prepare(number int) error
+	Start(number int, volume int, repeats int, done func()) error
+	Stop(number int)
+	Finish(number int)
+}
+
+// Sound effect numbers 1 and 2 are always the built in beeps
+const (
+	soundHighBeep = 1
+	soundLowBeep  = 2
+)
+
+const (
+	soundEffectPrepare = 1
+	soundEffectStart   = 2
+	soundEffectStop    = 3
+	soundEffectFinish  = 4
+)
+
+// soundVolume unpacks the volume operand, where 255 is the loudest. From V5 the high byte is the
+// number of times to play the sound, with 255 meaning forever.
+func soundVolume(operand word, version int) (int, int) {
+	volume := int(operand & 0xff)
+	if volume == 0xff || volume > sound.MaxVolume {
+		volume = sound.MaxVolume
+	}
+
+	if version < 5 {
+		return volume, sound.RepeatDefault
+	}
+
+	switch repeats := int(operand >> 8); repeats {
+	case 0:
+		return volume, 1
+	case 0xff:
+		return volume, sound.RepeatForever
+	default:
+		return volume, repeats
+	}
+}
+
+// soundFinished queues the routine to call when a sound ends. Routines run between instructions,
+// so the player can finish a sound from any goroutine.
+func (zmachine *ZMachine) soundFinished(routine word) func() {
+	return func() {
+		if routine == 0 {
+			return
+		}
+
+		select {
+		case zmachine.soundDone <- routine:
+		default:
+			// Too many sounds finished at once to keep up, so this one goes unannounced
+		}
+	}
+}
+
+func (zmachine *ZMachine) runSoundRoutines() error {
+	for {
+		select {
+		case routine := <-zmachine.soundDone:
+			_, err := zmachine.callRoutine(routine)
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package zmachine
+
+import (
+	"fmt"
+
+	"github.com/Drakmyth/golang-zmachine/debuginfo"
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+// symbolTable names things in diagnostics using the story's debug information
+type symbolTable struct {
+	info    *debuginfo.Info
+	globals map[VarNum]string
+}
+
+// UseDebugInfo names routines, variables, objects, attributes and properties in debug output
+// and error reports, using the debug information file Inform compiled alongside the story
+func (zmachine *ZMachine) UseDebugInfo(info *debuginfo.Info) {
+	table := &symbolTable{info: info, globals: map[VarNum]string{}}
+
+	globals := zmachine.Memory.GetGlobalsAddress()
+	for i := range int(MaxGlobalVarNum-MinGlobalVarNum) + 1 {
+		varnum := MinGlobalVarNum + VarNum(i)
+		if name, ok := info.Global(globals.OffsetWords(varnum.asGlobal())); ok {
+			table.globals[varnum] = name
+		}
+	}
+
+	zmachine.symbols = table
+	if zmachine.Objects != nil {
+		zmachine.Objects.symbols = table
+	}
+}
+
+// OperandKind says what a constant operand refers to, so it can be named
+type OperandKind uint8
+
+const (
+	OK_Number    OperandKind = 0
+	OK_Variable  OperandKind = 1
+	OK_Routine   OperandKind = 2
+	OK_Object    OperandKind = 3
+	OK_Attribute OperandKind = 4
+	OK_Property  OperandKind = 5
+)
+
+var operandKinds = map[string][]OperandKind{
+	"call":          {OK_Routine},
+	"clear_attr":    {OK_Object, OK_Attribute},
+	"dec":           {OK_Variable},
+	"dec_chk":       {OK_Variable},
+	"get_child":     {OK_Object},
+	"get_next_prop": {OK_Object, OK_Property},
+	"get_parent":    {OK_Object},
+	"get_prop":      {OK_Object, OK_Property},
+	"get_prop_addr": {OK_Object, OK_Property},
+	"get_sibling":   {OK_Object},
+	"inc":           {OK_Variable},
+	"inc_chk":       {OK_Variable},
+	"insert_obj":    {OK_Object, OK_Object},
+	"jin":           {OK_Object, OK_Object},
+	"load":          {OK_Variable},
+	"print_obj":     {OK_Object},
+	"pull":          {OK_Variable},
+	"put_prop":      {OK_Object, OK_Property},
+	"remove_obj":    {OK_Object},
+	"set_attr":      {OK_Object, OK_Attribute},
+	"store":         {OK_Variable},
+	"test_attr":     {OK_Object, OK_Attribute},
+}
+
+// describeOperand names a constant operand of an instruction, or returns "" if it has no name
+func (instruction Instruction) describeOperand(symbols *symbolTable, i int, value word) string {
+	if symbols == nil {
+		return ""
+	}
+
+	kinds := operandKinds[instruction.Name()]
+	if i >= len(kinds) {
+		return ""
+	}
+
+	info := symbols.info
+	switch kinds[i] {
+	case OK_Variable:
+		return instruction.variableName(symbols, VarNum(value))
+	case OK_Routine:
+		return info.RoutineName(value)
+	case OK_Object:
+		if name, ok := info.Object(int(value)); ok {
+			return "Object " + name
+		}
+	case OK_Attribute:
+		if name, ok := info.Attribute(int(value)); ok {
+			return "Attribute " + name
+		}
+	case OK_Property:
+		if name, ok := info.Property(int(value)); ok {
+			return "Property " + name
+		}
+	}
+	return ""
+}
+
+// variableName names a variable, using the local names of the routine the instruction is in
+func (instruction Instruction) variableName(symbols *symbolTable, varnum VarNum) string {
+	if symbols == nil {
+		return varnum.String()
+	}
+
+	if varnum.isLocal() {
+		routine, ok := symbols.info.RoutineAt(instruction.Address)
+		if ok && varnum.asLocal() < len(routine.Locals) && routine.Locals[varnum.asLocal()] != "" {
+			return routine.Locals[varnum.asLocal()]
+		}
+	} else if name, ok := symbols.globals[varnum]; ok {
+		return "g_" + name
+	}
+	return varnum.String()
+}
+
+// describeObject names an object for diagnostics, such as "Object 27 (brass_lantern)"
+func describeObject(symbols *symbolTable, id ObjectId) string {
+	if symbols != nil {
+		if name, ok := symbols.info.Object(int(id)); ok {
+			return fmt.Sprintf("Object %d (%s)", id, name)
+		}
+	}
+	return fmt.Sprintf("Object %d", id)
+}
+
+// describeProperty names a property for diagnostics, such as "Property 5 (description)"
+func describeProperty(symbols *symbolTable, id PropertyId) string {
+	if symbols != nil {
+		if name, ok := symbols.info.Property(int(id)); ok {
+			return fmt.Sprintf("Property %d (%s)", id, name)
+		}
+	}
+	return fmt.Sprintf("Property %d", id)
+}
+
+// describeAddress adds the routine and source line to an address, when they're known
+func describeAddress(symbols *symbolTable, address memory.Address) string {
+	description := fmt.Sprintf("%x", address)
+	if symbols == nil {
+		return description
+	}
+
+	if routine, ok := symbols.info.RoutineAt(address); ok {
+		description += " in " + routine.Name
+	}
+	if line, ok := symbols.info.LineAt(address); ok {
+		description += fmt.Sprintf(" (%s:%d)", line.File, line.Line)
+	}
+	return description
+}
@@ -0,0 +1,73 @@
+package zmachine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/debuginfo"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+// useTestSymbols loads debug information naming global 2, a local of a routine at 0x100, object
+// 27 and attribute 17
+func useTestSymbols(t *testing.T, zmachine *ZMachine) {
+	t.Helper()
+
+	global := int(zmachine.Memory.GetGlobalsAddress().OffsetWords(2))
+	info, err := debuginfo.Read(strings.NewReader(fmt.Sprintf(`<inform-story-file>
+		<attribute><identifier>light</identifier><value>17</value></attribute>
+		<object><identifier>brass_lantern</identifier><value>27</value></object>
+		<global-variable><identifier>location</identifier><address>%d</address></global-variable>
+		<routine><identifier>Main</identifier><value>128</value><address>256</address><byte-count>16</byte-count>
+			<local-variable><identifier>count</identifier><index>1</index></local-variable>
+		</routine>
+	</inform-story-file>`, global)))
+	testassert.NoError(t, err)
+
+	zmachine.UseDebugInfo(info)
+}
+
+func TestVariableName_UsesGlobalNames(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	instruction := Instruction{Address: 0x101}
+	testassert.Same(t, "g2", instruction.variableName(zmachine.symbols, 0x12))
+
+	useTestSymbols(t, zmachine)
+	testassert.Same(t, "g_location", instruction.variableName(zmachine.symbols, 0x12))
+	testassert.Same(t, "g3", instruction.variableName(zmachine.symbols, 0x13))
+
+	// Names belong to the machine that loaded them
+	testassert.Same(t, "g2", VarNum(0x12).String())
+	testassert.Same(t, "g2", instruction.variableName(newSaveTestMachine(t).symbols, 0x12))
+}
+
+func TestInstruction_String_UsesSymbols(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	useTestSymbols(t, zmachine)
+
+	zmachine.Memory.WriteByte(0x101, 0x0a) // test_attr #1b #11 ?~105
+	zmachine.Memory.WriteByte(0x102, 0x1b)
+	zmachine.Memory.WriteByte(0x103, 0x11)
+	zmachine.Memory.WriteByte(0x104, 0x42)
+	instruction, _, err := zmachine.readInstruction(0x101)
+	testassert.NoError(t, err)
+	testassert.Same(t, "0a TEST_ATTR Object brass_lantern Attribute light ?~105", instruction.describe(zmachine.symbols))
+	testassert.Same(t, "0a TEST_ATTR #1b #11 ?~105", instruction.String())
+
+	zmachine.Memory.WriteByte(0x101, 0x55) // sub local0 #01 -> g2
+	zmachine.Memory.WriteByte(0x102, 0x01)
+	zmachine.Memory.WriteByte(0x103, 0x01)
+	zmachine.Memory.WriteByte(0x104, 0x12)
+	instruction, _, err = zmachine.readInstruction(0x101)
+	testassert.NoError(t, err)
+	testassert.Same(t, "55 SUB count #01 -> g_location", instruction.describe(zmachine.symbols))
+}
+
+func TestDescribeObject(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	testassert.Same(t, "Object 27", describeObject(zmachine.symbols, 27))
+
+	useTestSymbols(t, zmachine)
+	testassert.Same(t, "Object 27 (brass_lantern)", describeObject(zmachine.symbols, 27))
+}
@@ -0,0 +1,120 @@
+package zmachine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+)
+
+// TraceFilter limits which instructions are traced. Zero values don't filter anything.
+type TraceFilter struct {
+	RoutineStart memory.Address // First routine address to trace, matched against the routine header
+	RoutineEnd   memory.Address // Last routine address to trace, inclusive
+	Opcodes      []string       // Opcode names to trace, such as "call_vs" or "je"
+}
+
+func (filter TraceFilter) matches(routine memory.Address, name string) bool {
+	if filter.RoutineEnd != 0 && (routine < filter.RoutineStart || routine > filter.RoutineEnd) {
+		return false
+	}
+	if len(filter.Opcodes) > 0 && !slices.ContainsFunc(filter.Opcodes, func(opcode string) bool {
+		return strings.EqualFold(opcode, name)
+	}) {
+		return false
+	}
+	return true
+}
+
+// TraceEntry is a single executed instruction, written to the trace as one line of JSON
+type TraceEntry struct {
+	PC       memory.Address `json:"pc"`
+	Opcode   string         `json:"opcode"`
+	Operands []word         `json:"operands"`
+	Store    *TraceStore    `json:"store,omitempty"`
+	Branch   *bool          `json:"branch,omitempty"` // Whether the branch was taken
+	Routine  memory.Address `json:"routine"`
+	Depth    int            `json:"depth"` // Number of frames on the stack, 1 in the main routine
+}
+
+type TraceStore struct {
+	Variable string `json:"variable"`
+	Value    *word  `json:"value,omitempty"` // Missing for calls, which store when the routine returns
+}
+
+// Tracer writes executed instructions to a file, for analysing a session after it ends
+type Tracer struct {
+	output  io.Writer
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+	filter  TraceFilter
+}
+
+func NewTracer(output io.Writer, filter TraceFilter) *Tracer {
+	buffer := bufio.NewWriter(output)
+	return &Tracer{output: output, buffer: buffer, encoder: json.NewEncoder(buffer), filter: filter}
+}
+
+// Close writes out any buffered entries, and closes the output if it can be closed. It returns
+// the first error from writing the trace.
+func (tracer *Tracer) Close() error {
+	err := tracer.buffer.Flush()
+	if closer, ok := tracer.output.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+// begin starts an entry for an instruction whose operands have been resolved, or returns nil
+// if the filter leaves it out
+func (tracer *Tracer) begin(zmachine *ZMachine, frame *Frame, instruction Instruction) *TraceEntry {
+	if tracer == nil {
+		return nil
+	}
+
+	name := instruction.Name()
+	if !tracer.filter.matches(frame.Routine, name) {
+		return nil
+	}
+
+	entry := &TraceEntry{
+		PC:      instruction.Address,
+		Opcode:  name,
+		Routine: frame.Routine,
+		Depth:   zmachine.Stack.Size(),
+	}
+	for _, operand := range instruction.Operands {
+		entry.Operands = append(entry.Operands, operand.asWord())
+	}
+	return entry
+}
+
+// finish records the outcome of the instruction and writes the entry. Write errors are kept by
+// the buffer and reported by Close, rather than stopping the game.
+func (tracer *Tracer) finish(zmachine *ZMachine, entry *TraceEntry, instruction Instruction, counterUpdated bool) {
+	if entry == nil {
+		return
+	}
+
+	depth := zmachine.Stack.Size()
+	if instruction.StoresResult() {
+		entry.Store = &TraceStore{Variable: instruction.variableName(zmachine.symbols, instruction.StoreVariable.Number)}
+		if depth == entry.Depth {
+			if value, err := instruction.StoreVariable.ReadInPlace(); err == nil {
+				entry.Store.Value = &value
+			}
+		}
+	}
+
+	if instruction.Branches() {
+		// Handlers only move the counter or leave the routine when the branch is taken
+		taken := counterUpdated || depth < entry.Depth
+		entry.Branch = &taken
+	}
+
+	tracer.encoder.Encode(entry)
+}
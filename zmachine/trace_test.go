@@ -0,0 +1,83 @@
+package zmachine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func readTrace(t *testing.T, buffer *bytes.Buffer) []TraceEntry {
+	t.Helper()
+
+	entries := make([]TraceEntry, 0)
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		entry := TraceEntry{}
+		testassert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestTracer_RecordsStoresAndBranches(t *testing.T) {
+	zmachine := newSaveTestMachine(t)
+	buffer := bytes.Buffer{}
+	zmachine.Tracer = NewTracer(&buffer, TraceFilter{})
+
+	zmachine.Stack.Frame(1).Counter = 0x100
+	zmachine.Memory.WriteByte(0x100, 0x14) // add #05 #07 -> sp
+	zmachine.Memory.WriteByte(0x101, 0x05)
+	zmachine.Memory.WriteByte(0x102, 0x07)
+	zmachine.Memory.WriteByte(0x103, 0x00)
+	zmachine.Memory.WriteByte(0x104, 0x01) // je #03 #03 ?10b
+	zmachine.Memory.WriteByte(0x105, 0x03)
+	zmachine.Memory.WriteByte(0x106, 0x03)
+	zmachine.Memory.WriteByte(0x107, 0xc5)
+
+	testassert.NoError(t, zmachine.executeNextInstruction())
+	testassert.NoError(t, zmachine.executeNextInstruction())
+	testassert.NoError(t, zmachine.Tracer.Close())
+
+	entries := readTrace(t, &buffer)
+	testassert.Same(t, 2, len(entries))
+
+	add := entries[0]
+	testassert.Same(t, memory.Address(0x100), add.PC)
+	testassert.Same(t, "add", add.Opcode)
+	testassert.Same(t, 2, len(add.Operands))
+	testassert.Same(t, "sp", add.Store.Variable)
+	testassert.Same(t, word(12), *add.Store.Value)
+	testassert.Same(t, 2, add.Depth)
+
+	je := entries[1]
+	testassert.Same(t, "je", je.Opcode)
+	testassert.True(t, *je.Branch)
+	testassert.Same(t, memory.Address(0x10b), zmachine.Stack.Frame(1).Counter)
+}
+
+func TestTraceFilter_Matches(t *testing.T) {
+	type spec struct {
+		filter  TraceFilter
+		routine memory.Address
+		opcode  string
+		matches bool
+	}
+
+	tests := map[string]spec{
+		"no filter":         {filter: TraceFilter{}, routine: 0x500, opcode: "je", matches: true},
+		"inside range":      {filter: TraceFilter{RoutineStart: 0x400, RoutineEnd: 0x500}, routine: 0x500, matches: true},
+		"outside range":     {filter: TraceFilter{RoutineStart: 0x400, RoutineEnd: 0x500}, routine: 0x501, matches: false},
+		"opcode listed":     {filter: TraceFilter{Opcodes: []string{"call_vs", "JE"}}, opcode: "je", matches: true},
+		"opcode not listed": {filter: TraceFilter{Opcodes: []string{"call_vs"}}, opcode: "je", matches: false},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			testassert.Same(t, s.matches, s.filter.matches(s.routine, s.opcode))
+		})
+	}
+}
@@ -3,7 +3,6 @@
 import (
 	"fmt"
 
-	"github.com/Drakmyth/golang-zmachine/assert"
 	"github.com/Drakmyth/golang-zmachine/memory"
 )
 
@@ -64,70 +63,61 @@ func (variable Variable) isGlobal() bool {
 	return variable.Number.isGlobal()
 }
 
-func (variable Variable) Read() word {
+// Read takes the variable's value, popping it when the variable is the stack
+func (variable Variable) Read() (word, error) {
 	zmachine := variable.zmachine
 
 	if variable.isStack() {
-		frame, err := zmachine.Stack.Peek()
-		assert.NoError(err, "Error peeking frame stack")
-		value, err := frame.Stack.Pop()
-		assert.NoError(err, "Error popping local stack")
-		return value
+		return zmachine.Stack.PopValue()
 	} else if variable.isLocal() {
-		frame, err := zmachine.Stack.Peek()
-		assert.NoError(err, "Error peeking frame stack")
-		return frame.Locals[variable.Number.asLocal()]
+		return zmachine.Stack.Local(variable.Number.asLocal())
 	} else {
 		global := zmachine.Memory.ReadWord(zmachine.Memory.GetGlobalsAddress().OffsetWords(variable.Number.asGlobal()))
-		return global
+		return global, nil
 	}
 }
 
-func (variable Variable) ReadInPlace() word {
+// ReadInPlace takes the variable's value, leaving the top of the stack in place
+func (variable Variable) ReadInPlace() (word, error) {
 	zmachine := variable.zmachine
 
 	if variable.isStack() {
-		frame, err := zmachine.Stack.Peek()
-		assert.NoError(err, "Error peeking frame stack")
-		value, err := frame.Stack.Peek()
-		assert.NoError(err, "Error peeking local stack")
-		return *value
+		value, err := zmachine.Stack.PeekValue()
+		if err != nil {
+			return 0, err
+		}
+		return *value, nil
 	}
 
 	return variable.Read()
 }
 
-func (variable *Variable) Write(value word) {
+// Write sets the variable's value, pushing it when the variable is the stack
+func (variable *Variable) Write(value word) error {
 	zmachine := variable.zmachine
 
 	if variable.isStack() {
-		frame, err := zmachine.Stack.Peek()
-		assert.NoError(err, "Error peeking frame stack")
-		frame.Stack.Push(value)
+		return zmachine.Stack.PushValue(value)
 	} else if variable.isLocal() {
-		frame, err := zmachine.Stack.Peek()
-		assert.NoError(err, "Error peeking frame stack")
-		frame.Locals[variable.Number.asLocal()] = value
+		return zmachine.Stack.SetLocal(variable.Number.asLocal(), value)
 	} else {
 		zmachine.Memory.WriteWord(zmachine.Memory.GetGlobalsAddress().OffsetWords(variable.Number.asGlobal()), value)
+		return nil
 	}
 }
 
-func (variable *Variable) WriteInPlace(value word) {
+// WriteInPlace sets the variable's value, replacing the top of the stack instead of pushing
+func (variable *Variable) WriteInPlace(value word) error {
 	zmachine := variable.zmachine
 
 	if variable.isStack() {
-		frame, err := zmachine.Stack.Peek()
-		assert.NoError(err, "Error peeking frame stack")
-		stackSize := frame.Stack.Size()
-		if stackSize > 0 {
-			frame.Stack[stackSize-1] = value
-		} else {
-			frame.Stack.Push(value)
+		top, err := zmachine.Stack.PeekValue()
+		if err == nil {
+			*top = value
+			return nil
 		}
-	} else {
-		variable.Write(value)
 	}
+	return variable.Write(value)
 }
 
 func (zmachine *ZMachine) getVariable(index VarNum) Variable {
@@ -66,7 +66,8 @@ func TestVariable_Read_Global(t *testing.T) {
 	expected := word(0xbeef)
 
 	zmachine.Memory.WriteWord(address, expected)
-	actual := zmachine.getVariable(global_num).Read()
+	actual, err := zmachine.getVariable(global_num).Read()
+	testassert.NoError(t, err)
 
 	testassert.Same(t, expected, actual)
 }
@@ -83,8 +84,9 @@ func TestVariable_Write_Global(t *testing.T) {
 	zmachine.Memory.WriteWord(address, initial)
 
 	variable := zmachine.getVariable(global_num)
-	variable.Write(expected)
-	actual := variable.Read()
+	testassert.NoError(t, variable.Write(expected))
+	actual, err := variable.Read()
+	testassert.NoError(t, err)
 
 	testassert.Same(t, expected, actual)
 }
@@ -1,45 +1,111 @@
 package zmachine
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"math/rand/v2"
 	"os"
-	"time"
 
 	"github.com/Drakmyth/golang-zmachine/assert"
 	"github.com/Drakmyth/golang-zmachine/memory"
+	"github.com/Drakmyth/golang-zmachine/quetzal"
 	"github.com/Drakmyth/golang-zmachine/screen"
-	"github.com/Drakmyth/golang-zmachine/stack"
+	"github.com/Drakmyth/golang-zmachine/sound"
 	"github.com/Drakmyth/golang-zmachine/zstring"
 )
 
 type word = uint16
 
 type ZMachine struct {
-	Debug   bool
-	Memory  *memory.Memory
-	Random  *rand.Rand
-	Stack   stack.Stack[Frame]
-	Charset zstring.Charset
-	Screen  *screen.Screen
+	Debug      bool
+	ErrorLevel ErrorLevel
+	Memory     *memory.Memory
+	Random     *RNG
+	Stack      CallStack
+	Objects    *ObjectTable
+	Charset    zstring.Charset
+	Unicode    zstring.UnicodeTable
+	Screen     *screen.Screen
+	Sound      SoundPlayer
+	Tracer     *Tracer       // Records executed instructions, when tracing is turned on
+	Profiler   *Profiler     // Counts the work done by each routine, when profiling is turned on
+	soundDone  chan word     // Routines to call for sounds that have finished playing
+	undo       *quetzal.Save // State at the previous prompt, restored by /undo
+	prompt     *quetzal.Save // State at the current prompt
+	reading    *Instruction  // The read waiting for input, while meta-commands run
+	transcript *os.File
+	script     []string        // Commands still to be replayed from a /script file
+	reported   map[string]bool // Errors already reported, for EL_ReportOnce
+	recent     *instructionRing
+	before     CallStack // The stack as it was before the outermost running instruction
+	running    int       // Instructions running, more than 1 while handlers call routines
+	decoded    *instructionCache
+	symbols    *symbolTable   // Names from the story's debug information, when it's loaded
+	exit       func(code int) // Ends the process once the machine has shut down
+	step       func() error   // Runs the next instruction, for routines the interpreter calls itself
 }
 
-type Frame struct {
-	Counter        memory.Address
-	Stack          stack.Stack[word]
-	Locals         []word
-	DiscardReturn  bool
-	ReturnVariable Variable
-}
-
-func (zmachine *ZMachine) endCurrentFrame(value word) {
+// endCurrentFrame returns from the running routine, storing its result in the caller
+func (zmachine *ZMachine) endCurrentFrame(value word) error {
 	frame, err := zmachine.Stack.Pop()
-	assert.NoError(err, "Error popping frame stack")
+	if err != nil {
+		return err
+	}
 
 	if !frame.DiscardReturn {
-		frame.ReturnVariable.Write(value)
+		return frame.ReturnVariable.Write(value)
+	}
+	return nil
+}
+
+// pushFrame starts a routine call, with its locals initialized from the routine header and
+// then overwritten by any supplied arguments
+func (zmachine *ZMachine) pushFrame(frame Frame, packed_address word, args []word) error {
+	routineAddr := zmachine.Memory.RoutinePackedAddress(packed_address)
+	num_locals, next_address := zmachine.Memory.ReadByteNext(routineAddr)
+
+	frame.Routine = routineAddr
+	frame.ArgCount = len(args)
+	locals, err := zmachine.Stack.Push(frame, int(num_locals))
+	if err != nil {
+		return err
 	}
+
+	if zmachine.Memory.GetVersion() < 5 {
+		for i := range locals {
+			locals[i], next_address = zmachine.Memory.ReadWordNext(next_address)
+		}
+	}
+	copy(locals, args)
+
+	top, _ := zmachine.Stack.Peek()
+	top.Counter = next_address
+	return nil
+}
+
+// callRoutine runs a routine to completion on a nested frame and returns its result. This is
+// used for routines the interpreter calls itself, such as timed input interrupts.
+func (zmachine *ZMachine) callRoutine(packed_address word, args ...word) (word, error) {
+	if packed_address == 0 {
+		return 0, nil
+	}
+
+	depth := zmachine.Stack.Size()
+	err := zmachine.pushFrame(Frame{ReturnVariable: zmachine.getVariable(0)}, packed_address, args)
+	if err != nil {
+		return 0, err
+	}
+
+	// Handlers like read call routines, so calling back into the interpreter through a method
+	// value keeps the opcode tables from depending on themselves
+	for zmachine.Stack.Size() > depth {
+		err := zmachine.step()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// The result is returned onto the caller's stack, so take it back off
+	return zmachine.Stack.PopValue()
 }
 
 func Load(story_path string) (*ZMachine, error) {
@@ -48,10 +114,21 @@ func Load(story_path string) (*ZMachine, error) {
 	})
 	assert.NoError(err, "Error loading story")
 
-	stack := append(make([]Frame, 0, 1024), Frame{Counter: m.GetInitialProgramCounter()})
+	return NewZMachine(m, screen.NewScreen())
+}
+
+// NewZMachine prepares a story to run on a screen, which may be a simulation for running
+// without a terminal
+func NewZMachine(m *memory.Memory, s *screen.Screen) (*ZMachine, error) {
+	stack := NewCallStack()
+	_, err := stack.Push(Frame{Counter: m.GetInitialProgramCounter()}, 0)
+	assert.NoError(err, "Error starting main routine: %v", err)
 
 	version := m.GetVersion()
 
+	unicode, err := m.GetUnicodeTable()
+	assert.NoError(err, "Error loading unicode translation table")
+
 	alphabetAddress := memory.Address(m.ReadWord(memory.Addr_ROM_A_AlphabetTable))
 
 	ctrlchars := zstring.GetDefaultCtrlCharMapping(version)
@@ -61,47 +138,216 @@ func Load(story_path string) (*ZMachine, error) {
 		charset, err = zstring.NewStaticCharset(alphabet, ctrlchars)
 		assert.NoError(err, "Error instantiating static charset")
 	} else {
-		alphabetHandler := func() []rune { return bytes.Runes(m.GetBytes(memory.Addr_ROM_A_AlphabetTable, 78)) }
+		alphabetHandler := func() []rune {
+			// Alphabet table entries are ZSCII, not Unicode
+			alphabet := make([]rune, 0, 78)
+			for _, zc := range m.GetBytes(alphabetAddress, 78) {
+				r, err := unicode.ToRune(zstring.ZSCII(zc))
+				if err != nil {
+					r = '?'
+				}
+				alphabet = append(alphabet, r)
+			}
+			return alphabet
+		}
 		charset, err = zstring.NewDynamicCharset(alphabetHandler, ctrlchars)
 		assert.NoError(err, "Error instantiating dynamic charset")
 	}
 
-	seed := uint64(time.Now().UnixMilli())
-	rand := rand.New(rand.NewPCG(seed, seed))
-
 	zmachine := ZMachine{
 		Memory:  m,
-		Random:  rand,
+		Random:  NewRNG(ClockSeed),
 		Stack:   stack,
+		Objects: NewObjectTable(m),
 		Charset: charset,
-		Screen:  screen.NewScreen(),
+		Unicode: unicode,
+		Screen:  s,
 	}
+	zmachine.Sound = sound.LogPlayer{Bell: zmachine.Screen.Beep}
+	zmachine.soundDone = make(chan word, 8)
+	zmachine.recent = &instructionRing{}
+	zmachine.exit = os.Exit
+	zmachine.step = zmachine.executeNextInstruction
+	zmachine.decoded = newInstructionCache(m)
+
+	zmachine.advertiseCapabilities()
+	zmachine.Screen.OnResize = zmachine.screenResized
+	zmachine.Screen.OnWait = zmachine.waitingForInput
 
 	return &zmachine, nil
 }
 
+// advertiseCapabilities updates the header with the features this interpreter and screen support
+func (zmachine *ZMachine) advertiseCapabilities() {
+	m := zmachine.Memory
+	version := m.GetVersion()
+	if version < 4 {
+		return
+	}
+
+	m.ClearFlag1Bits(memory.Flags1_ColorsAvailable | memory.Flags1_PictureDisplayingAvailable | memory.Flags1_SoundEffectsAvailable)
+	m.SetFlag1Bits(memory.Flags1_BoldfaceAvailable | memory.Flags1_ItalicAvailable | memory.Flags1_FixedSpaceStyleAvailable |
+		memory.Flags1_TimedKeyboardInputAvailable)
+
+	if version >= 5 && zmachine.Screen.ColoursAvailable() {
+		m.SetFlag1Bits(memory.Flags1_ColorsAvailable)
+		foreground, background := zmachine.Screen.DefaultColours()
+		m.WriteByte(memory.Addr_IROM_B_BGColor, byte(background))
+		m.WriteByte(memory.Addr_IROM_B_FGColor, byte(foreground))
+	}
+
+	// Stories ask for sound effects, and are told whether they can have them
+	if version >= 5 && !zmachine.Sound.Sampled() {
+		m.ClearFlag2Bits(memory.Flags2_UseSoundEffects)
+	}
+	if version == 6 && zmachine.Sound.Sampled() {
+		m.SetFlag1Bits(memory.Flags1_SoundEffectsAvailable)
+	}
+
+	zmachine.writeScreenDimensions(zmachine.Screen.Size())
+}
+
+// writeScreenDimensions records the screen size in the header. Each character is one unit, and
+// a height of 255 would mean infinite so the largest usable height is 254.
+func (zmachine *ZMachine) writeScreenDimensions(width int, height int) {
+	m := zmachine.Memory
+	version := m.GetVersion()
+	if version < 4 {
+		return
+	}
+
+	m.WriteByte(memory.Addr_IROM_B_ScreenHeight, byte(min(height, 254)))
+	m.WriteByte(memory.Addr_IROM_B_ScreenWidth, byte(min(width, 255)))
+
+	if version >= 5 {
+		m.WriteWord(memory.Addr_IROM_W_ScreenWidthUnits, word(width))
+		m.WriteWord(memory.Addr_IROM_W_ScreenHeightUnits, word(height))
+		m.WriteByte(memory.Addr_IROM_B_FontHeight, 1)
+		m.WriteByte(memory.Addr_IROM_B_FontWidth, 1)
+	}
+}
+
+// screenResized updates the header after the terminal changes size, and asks V6 stories to
+// redraw since only they lay out the screen themselves
+func (zmachine *ZMachine) screenResized(width int, height int) {
+	zmachine.writeScreenDimensions(width, height)
+
+	if zmachine.Memory.GetVersion() == 6 {
+		zmachine.Memory.SetFlag2Bits(memory.Flags2_RequestScreenRedraw)
+	}
+}
+
+// waitingForInput keeps the time the player takes to answer out of the profile
+func (zmachine *ZMachine) waitingForInput(waiting bool) {
+	zmachine.Profiler.wait(waiting)
+}
+
 func (zmachine ZMachine) Shutdown(exit int) {
+	if zmachine.transcript != nil {
+		zmachine.transcript.Close()
+	}
+	if zmachine.Tracer != nil {
+		zmachine.Tracer.Close()
+	}
+	if zmachine.Profiler != nil {
+		zmachine.Profiler.Close()
+	}
 	zmachine.Screen.End()
-	os.Exit(exit)
+	zmachine.exit(exit)
+}
+
+// UseSoundPlayer replaces the player for sound effects, letting the story know what it supports
+func (zmachine *ZMachine) UseSoundPlayer(player SoundPlayer) {
+	zmachine.Sound = player
+	zmachine.advertiseCapabilities()
 }
 
-func (zmachine ZMachine) Run() error {
+func (zmachine *ZMachine) Run() error {
 	for {
-		err := zmachine.executeNextInstruction()
+		err := zmachine.runSoundRoutines()
+		if err != nil {
+			return err
+		}
+
+		err = zmachine.executeNextInstruction()
+		if errors.Is(err, screen.ErrQuit) {
+			// Quitting at a prompt ends the story the same way quit does
+			zmachine.Shutdown(0)
+			return nil
+		}
+		if err != nil {
+			err = zmachine.handleError(err)
+		}
 		if err != nil {
 			return err
 		}
 	}
 }
 
-func (zmachine *ZMachine) executeNextInstruction() error {
+// executeNextInstruction runs the instruction at the current frame's counter. Failures, including
+// panics, are returned as a *ZMachineError.
+func (zmachine *ZMachine) executeNextInstruction() (err error) {
+	// Errors report the machine as it was before the outermost instruction began, which is a
+	// state the instruction can run again from. Instructions run by routines a handler calls
+	// are part of that instruction.
+	if zmachine.running == 0 {
+		zmachine.Stack.CopyTo(&zmachine.before)
+		zmachine.Memory.Checkpoint()
+	}
+
 	frame, err := zmachine.Stack.Peek()
-	assert.NoError(err, "Error peeking instruction stack")
+	if err != nil {
+		zerr := zmachine.newError(0, err)
+		zerr.Fatal = true
+		return zerr
+	}
+
+	zmachine.Profiler.instruction(&zmachine.Stack)
+
+	pc := frame.Counter
+	decoded := false
+	var instruction Instruction
+	zmachine.running++
+	defer func() {
+		zmachine.running--
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		if err == nil || errors.As(err, new(*ZMachineError)) {
+			// Errors from routines the instruction called already describe where they happened
+			return
+		}
+
+		finishedErr := &finishedError{}
+		done := errors.As(err, &finishedErr)
+		if done {
+			err = finishedErr.err
+		}
 
-	instruction, next_address := zmachine.readInstruction(frame.Counter)
+		zerr := zmachine.newError(pc, err)
+		zerr.Stack, zerr.Memory = zmachine.before.Clone(), zmachine.Memory.DynamicMemoryAtCheckpoint()
+		zerr.done = done
+		zerr.Opcode = instruction.Opcode
+		zerr.next = instruction.end(zmachine.Memory)
+		if decoded && instruction.StoresResult() {
+			zerr.store = &instruction.StoreVariable
+		}
+		if !decoded {
+			zerr.Opcode = zmachine.peekOpcode(pc)
+			zerr.Fatal = true
+		}
+		err = zerr
+	}()
+
+	instruction, next_address, err := zmachine.decodeInstruction(pc)
+	if err != nil {
+		return err
+	}
+	decoded = true
+	zmachine.recent.add(instruction)
 
 	if zmachine.Debug {
-		fmt.Printf("%x: %s\n", frame.Counter, instruction)
+		fmt.Printf("%x: %s\n", pc, instruction.describe(zmachine.symbols))
 	}
 
 	for i, optype := range instruction.OperandTypes {
@@ -109,18 +355,25 @@ func (zmachine *ZMachine) executeNextInstruction() error {
 			continue
 		}
 
-		variable := zmachine.getVariable(VarNum(instruction.Operands[i]))
-		instruction.Operands[i] = Operand(variable.Read())
+		value, err := zmachine.getVariable(VarNum(instruction.Operands[i])).Read()
+		if err != nil {
+			return err
+		}
+		instruction.Operands[i] = Operand(value)
 	}
 
+	entry := zmachine.Tracer.begin(zmachine, frame, instruction)
+
 	counter_updated, err := instruction.Handler(zmachine, instruction)
-	if err != nil {
+	if err != nil && !errors.As(err, new(*finishedError)) {
 		return err
 	}
 
+	zmachine.Tracer.finish(zmachine, entry, instruction, counter_updated)
+
 	if !counter_updated {
 		frame.Counter = next_address
 	}
 
-	return nil
+	return err
 }
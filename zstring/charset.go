@@ -37,6 +37,7 @@ type Charset interface {
 	PrintRune(zc ZChar) (rune, error)
 	GetControlCharacter(zc ZChar) (ctrlchar, error)
 	IsA2() bool
+	Alphabet() ([]rune, error)
 }
 
 type charset struct {
@@ -122,6 +123,18 @@ func (c dynamicCharset) PrintRune(zc ZChar) (rune, error) {
 	return c.printRune(alphabet, zc)
 }
 
+func (c staticCharset) Alphabet() ([]rune, error) {
+	return c.alphabet, nil
+}
+
+func (c dynamicCharset) Alphabet() ([]rune, error) {
+	alphabet := c.getAlphabet()
+	if len(alphabet) != 78 {
+		return nil, errors.New("Invalid alphabet table length")
+	}
+	return alphabet, nil
+}
+
 func (c *charset) printRune(alphabet []rune, zc ZChar) (rune, error) {
 	if zc < 6 {
 		return '\x00', errors.New("Control ZCharacter not translatable to rune")
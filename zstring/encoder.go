@@ -0,0 +1,142 @@
+package zstring
+
+import (
+	"errors"
+	"slices"
+)
+
+const zcharPadding ZChar = 5
+
+func GetDictionaryWordLength(version int) int {
+	if version <= 3 {
+		return 6
+	}
+	return 9
+}
+
+// EncodeWord encodes text the same way the story's dictionary does, truncating or
+// padding the result to the dictionary word length for the version
+func EncodeWord(text []rune, charset Charset, unicode UnicodeTable, version int) (ZString, error) {
+	length := GetDictionaryWordLength(version)
+	zchars, err := encodeZCharacters(text, charset, unicode, version, length)
+	if err != nil {
+		return ZString{}, err
+	}
+
+	for len(zchars) < length {
+		zchars = append(zchars, zcharPadding)
+	}
+
+	return packZCharacters(zchars[:length]), nil
+}
+
+// EncodeText encodes the whole of a string, as printed by print and print_paddr. Only stories
+// are expected to hold strings, so this is for building stories rather than running them.
+func EncodeText(text []rune, charset Charset, unicode UnicodeTable, version int) (ZString, error) {
+	zchars, err := encodeZCharacters(text, charset, unicode, version, -1)
+	if err != nil {
+		return ZString{}, err
+	}
+
+	// Even an empty string needs a word to mark its end
+	if len(zchars) == 0 {
+		zchars = append(zchars, zcharPadding)
+	}
+
+	return packZCharacters(zchars), nil
+}
+
+// encodeZCharacters converts text to Z-characters, stopping once there are at least limit of
+// them unless limit is negative
+func encodeZCharacters(text []rune, charset Charset, unicode UnicodeTable, version int, limit int) ([]ZChar, error) {
+	alphabet, err := charset.Alphabet()
+	if err != nil {
+		return nil, err
+	}
+
+	shiftA1, err := findControlCharacter(charset, CTRL_Shift)
+	if err != nil {
+		return nil, err
+	}
+	shiftA2, err := findControlCharacter(charset, CTRL_Backshift)
+	if err != nil {
+		return nil, err
+	}
+
+	zchars := make([]ZChar, 0, max(limit+3, len(text)))
+
+	for _, r := range text {
+		if limit >= 0 && len(zchars) >= limit {
+			break
+		}
+
+		if r == ' ' {
+			zchars = append(zchars, 0)
+			continue
+		}
+
+		// V1 has a control character for new-line, while later versions use A2 character 7
+		if r == '\n' {
+			if newline, err := findControlCharacter(charset, CTRL_NewLine); err == nil {
+				zchars = append(zchars, newline)
+			} else {
+				zchars = append(zchars, shiftA2, 7)
+			}
+			continue
+		}
+
+		index := slices.Index(alphabet, r)
+		row := index / 26
+		column := ZChar(index%26) + 6
+
+		// A2 character 6 is the multibyte escape, and from V2 onward character 7 is always new-line
+		if row == 2 && (column == 6 || (column == 7 && version > 1)) {
+			index = -1
+		}
+
+		switch {
+		case index < 0:
+			zc, err := unicode.ToZSCII(r)
+			if err != nil {
+				return nil, err
+			}
+			zchars = append(zchars, shiftA2, 6, ZChar(zc>>5)&0b11111, ZChar(zc)&0b11111)
+		case row == 0:
+			zchars = append(zchars, column)
+		case row == 1:
+			zchars = append(zchars, shiftA1, column)
+		default:
+			zchars = append(zchars, shiftA2, column)
+		}
+	}
+
+	return zchars, nil
+}
+
+func findControlCharacter(charset Charset, target ctrlchar) (ZChar, error) {
+	for zc := ZChar(0); zc < 6; zc++ {
+		ctrl, err := charset.GetControlCharacter(zc)
+		if err == nil && ctrl == target {
+			return zc, nil
+		}
+	}
+
+	return 0, errors.New("Charset has no matching control character")
+}
+
+func packZCharacters(zchars []ZChar) ZString {
+	for len(zchars)%3 != 0 {
+		zchars = append(zchars, zcharPadding)
+	}
+
+	data := make(ZString, 0, len(zchars)*2/3)
+	for i := 0; i < len(zchars); i += 3 {
+		zword := word(zchars[i])<<10 | word(zchars[i+1])<<5 | word(zchars[i+2])
+		if i+3 >= len(zchars) {
+			zword |= 1 << 15
+		}
+		data = append(data, byte(zword>>8), byte(zword))
+	}
+
+	return data
+}
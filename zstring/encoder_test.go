@@ -0,0 +1,88 @@
+package zstring
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func TestEncodeWord_RoundTrip(t *testing.T) {
+	type spec struct {
+		version  int
+		input    string
+		expected string
+	}
+
+	tests := map[string]spec{
+		"v3 short word":     {version: 3, input: "lamp", expected: "lamp"},
+		"v3 truncated word": {version: 3, input: "lantern", expected: "lanter"},
+		"v3 punctuation":    {version: 3, input: ",", expected: ","},
+		"v5 long word":      {version: 5, input: "lantern", expected: "lantern"},
+		"v5 digits":         {version: 5, input: "x99", expected: "x99"},
+		"v5 escaped":        {version: 5, input: "café", expected: "café"},
+		"v5 truncated":      {version: 5, input: "abracadabra", expected: "abracadab"},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			charset, err := NewStaticCharset(GetDefaultAlphabet(s.version), GetDefaultCtrlCharMapping(s.version))
+			testassert.NoError(t, err)
+			unicode := GetDefaultUnicodeTable(s.version)
+
+			encoded, err := EncodeWord([]rune(s.input), charset, unicode, s.version)
+			testassert.NoError(t, err)
+			testassert.Same(t, GetDictionaryWordLength(s.version)*2/3, encoded.LenBytes())
+
+			actual, err := NewParser(charset, unicode, s.version, nil).Parse(encoded)
+			testassert.NoError(t, err)
+			testassert.Same(t, s.expected, actual)
+		})
+	}
+}
+
+func TestEncodeWord_KnownEncoding(t *testing.T) {
+	version := 3
+	charset, err := NewStaticCharset(GetDefaultAlphabet(version), GetDefaultCtrlCharMapping(version))
+	testassert.NoError(t, err)
+
+	// "the" pads to t h e 5 5 5
+	encoded, err := EncodeWord([]rune("the"), charset, GetDefaultUnicodeTable(version), version)
+	testassert.NoError(t, err)
+	testassert.Same(t, 4, len(encoded))
+	testassert.Same(t, byte(0x65), encoded[0])
+	testassert.Same(t, byte(0xaa), encoded[1])
+	testassert.Same(t, byte(0x94), encoded[2])
+	testassert.Same(t, byte(0xa5), encoded[3])
+}
+
+func TestEncodeText_RoundTrip(t *testing.T) {
+	type spec struct {
+		version int
+		input   string
+	}
+
+	tests := map[string]spec{
+		"v1 new-line":  {version: 1, input: "Line one\nLine two"},
+		"v1 digits":    {version: 1, input: "Room 101\nRoom 102"},
+		"v3 sentence":  {version: 3, input: "You see a brass lantern here."},
+		"v3 new-line":  {version: 3, input: "West of House\nYou are standing."},
+		"v3 empty":     {version: 3, input: ""},
+		"v5 escaped":   {version: 5, input: "Café (1984)"},
+		"v5 long text": {version: 5, input: "abracadabra abracadabra abracadabra"},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			charset, err := NewStaticCharset(GetDefaultAlphabet(s.version), GetDefaultCtrlCharMapping(s.version))
+			testassert.NoError(t, err)
+			unicode := GetDefaultUnicodeTable(s.version)
+
+			encoded, err := EncodeText([]rune(s.input), charset, unicode, s.version)
+			testassert.NoError(t, err)
+
+			actual, err := NewParser(charset, unicode, s.version, nil).Parse(encoded)
+			testassert.NoError(t, err)
+			testassert.Same(t, s.input, actual)
+		})
+	}
+}
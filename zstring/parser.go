@@ -28,6 +28,8 @@
 
 type Parser struct {
 	charset                 Charset
+	unicode                 UnicodeTable
+	version                 int
 	pendingAbbreviationBank int
 	multibyteState          int
 	multibyteValue          uint16
@@ -35,9 +37,11 @@ type Parser struct {
 	getAbbreviation         GetAbbreviationHandler
 }
 
-func NewParser(charset Charset, abbrevHandler GetAbbreviationHandler) Parser {
+func NewParser(charset Charset, unicode UnicodeTable, version int, abbrevHandler GetAbbreviationHandler) Parser {
 	return Parser{
 		charset:                 charset,
+		unicode:                 unicode,
+		version:                 version,
 		pendingAbbreviationBank: 0,
 		multibyteState:          0,
 		multibyteValue:          0x00,
@@ -61,12 +65,18 @@ func (p Parser) Parse(data ZString) (string, error) {
 		}
 
 		if p.UseAbbreviations && p.pendingAbbreviationBank > 0 {
-			p.processAbbreviation(p.pendingAbbreviationBank, int(zc), &builder)
+			err := p.processAbbreviation(p.pendingAbbreviationBank, int(zc), &builder)
+			if err != nil {
+				return "", err
+			}
 			continue
 		}
 
 		if zc < 6 {
-			p.processControlCharacter(zc, &builder)
+			err := p.processControlCharacter(zc, &builder)
+			if err != nil {
+				return "", err
+			}
 			continue
 		}
 
@@ -78,10 +88,13 @@ func (p Parser) Parse(data ZString) (string, error) {
 
 		switch zc {
 		case 7:
-			// if p.charset.currentCharset == 2 {
-			// TODO: Override table value with newline
-			//  continue
-			// }
+			if p.charset.IsA2() && p.version > 1 {
+				// From V2, A2 character 7 is always a new-line, regardless of the alphabet table.
+				// V1 has a control character for new-line instead, and prints '0' here.
+				builder.WriteRune('\n')
+				p.charset.Reset()
+				continue
+			}
 			fallthrough
 		default:
 			r, err := p.charset.PrintRune(zc)
@@ -105,7 +118,7 @@ func (p *Parser) processMultibyte(zc ZChar, builder *strings.Builder) {
 		p.multibyteState++
 	case 2:
 		p.multibyteValue = p.multibyteValue | uint16(zc)
-		builder.WriteRune(rune(p.multibyteValue))
+		builder.WriteString(p.unicode.Translate(ZSCII(p.multibyteValue)))
 		p.multibyteState = 0
 	}
 }
@@ -0,0 +1,152 @@
+package zstring
+
+import (
+	"errors"
+	"slices"
+	"unicode"
+)
+
+type ZSCII word
+
+const (
+	ZSCII_Null          ZSCII = 0
+	ZSCII_Tab           ZSCII = 9  // V6 output only
+	ZSCII_SentenceSpace ZSCII = 11 // V6 output only
+	ZSCII_NewLine       ZSCII = 13
+	ZSCII_FirstExtra    ZSCII = 155
+	ZSCII_LastExtra     ZSCII = 251
+)
+
+// Input-only codes
+const (
+	ZSCII_Delete      ZSCII = 8
+	ZSCII_Escape      ZSCII = 27
+	ZSCII_CursorUp    ZSCII = 129
+	ZSCII_CursorDown  ZSCII = 130
+	ZSCII_CursorLeft  ZSCII = 131
+	ZSCII_CursorRight ZSCII = 132
+	ZSCII_F1          ZSCII = 133 // F1-F12 are sequential through 144
+	ZSCII_Keypad0     ZSCII = 145 // Keypad 0-9 are sequential through 154
+	ZSCII_MenuClick   ZSCII = 252
+	ZSCII_DoubleClick ZSCII = 253
+	ZSCII_SingleClick ZSCII = 254
+)
+
+// IsFunctionKey reports whether the code is one of the keys a terminating characters table can
+// select all of with 255: the cursor, function and keypad keys, and mouse clicks
+func (zc ZSCII) IsFunctionKey() bool {
+	return ZSCII_CursorUp <= zc && zc <= ZSCII_Keypad0+9 || ZSCII_MenuClick <= zc && zc <= ZSCII_SingleClick
+}
+
+// Unicode translations for ZSCII 155-223 used when the story doesn't provide its own table
+var defaultExtraCharacters = []rune{
+	'ä', 'ö', 'ü', 'Ä', 'Ö', 'Ü', 'ß', '»', '«', 'ë', 'ï', 'ÿ', 'Ë', 'Ï', 'á', 'é', 'í', 'ó', 'ú', 'ý', 'Á', 'É', 'Í',
+	'Ó', 'Ú', 'Ý', 'à', 'è', 'ì', 'ò', 'ù', 'À', 'È', 'Ì', 'Ò', 'Ù', 'â', 'ê', 'î', 'ô', 'û', 'Â', 'Ê', 'Î', 'Ô', 'Û',
+	'å', 'Å', 'ø', 'Ø', 'ã', 'ñ', 'õ', 'Ã', 'Ñ', 'Õ', 'æ', 'Æ', 'ç', 'Ç', 'þ', 'ð', 'Þ', 'Ð', '£', 'œ', 'Œ', '¡', '¿',
+}
+
+/*
+ * ZSCII Output Codes
+ *   Code    | Meaning
+ *   --------|--------------------------------------
+ *   0       | Null, prints nothing
+ *   9       | Tab (V6 only)
+ *   11      | Sentence space (V6 only)
+ *   13      | New-Line
+ *   32-126  | Standard ASCII
+ *   155-251 | Extra characters, from the Unicode translation table
+ */
+
+type UnicodeTable struct {
+	version int
+	extras  []rune
+}
+
+func NewUnicodeTable(version int, extras []rune) (UnicodeTable, error) {
+	if len(extras) > int(ZSCII_LastExtra-ZSCII_FirstExtra)+1 {
+		return UnicodeTable{}, errors.New("Unicode translation table too long")
+	}
+
+	return UnicodeTable{
+		version: version,
+		extras:  extras,
+	}, nil
+}
+
+func GetDefaultUnicodeTable(version int) UnicodeTable {
+	return UnicodeTable{
+		version: version,
+		extras:  defaultExtraCharacters,
+	}
+}
+
+func (t UnicodeTable) ToRune(zc ZSCII) (rune, error) {
+	switch {
+	case zc == ZSCII_Null:
+		return '\x00', errors.New("ZSCII null has no printable representation")
+	case zc == ZSCII_Tab && t.version == 6:
+		return '\t', nil
+	case zc == ZSCII_SentenceSpace && t.version == 6:
+		return ' ', nil
+	case zc == ZSCII_NewLine:
+		return '\n', nil
+	case 32 <= zc && zc <= 126:
+		return rune(zc), nil
+	case ZSCII_FirstExtra <= zc && int(zc-ZSCII_FirstExtra) < len(t.extras):
+		return t.extras[zc-ZSCII_FirstExtra], nil
+	}
+
+	return '\x00', errors.New("ZSCII character undefined for output")
+}
+
+// Translate converts a ZSCII character into printable text, dropping nulls
+// and replacing undefined characters with '?'
+func (t UnicodeTable) Translate(zc ZSCII) string {
+	if zc == ZSCII_Null {
+		return ""
+	}
+
+	r, err := t.ToRune(zc)
+	if err != nil {
+		return "?"
+	}
+	return string(r)
+}
+
+func (t UnicodeTable) ToZSCII(r rune) (ZSCII, error) {
+	switch {
+	case r == '\n':
+		return ZSCII_NewLine, nil
+	case 32 <= r && r <= 126:
+		return ZSCII(r), nil
+	}
+
+	index := slices.Index(t.extras, r)
+	if index < 0 {
+		return ZSCII_Null, errors.New("Rune has no ZSCII representation")
+	}
+	return ZSCII_FirstExtra + ZSCII(index), nil
+}
+
+// ToLower lowercases ASCII letters directly and extra characters via their Unicode
+// lowercase form, provided the lowercase form is also present in the table
+func (t UnicodeTable) ToLower(zc ZSCII) ZSCII {
+	if 'A' <= zc && zc <= 'Z' {
+		return zc + ('a' - 'A')
+	}
+
+	if zc < ZSCII_FirstExtra {
+		return zc
+	}
+
+	r, err := t.ToRune(zc)
+	if err != nil {
+		return zc
+	}
+
+	lower, err := t.ToZSCII(unicode.ToLower(r))
+	if err != nil {
+		return zc
+	}
+	return lower
+}
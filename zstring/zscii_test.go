@@ -0,0 +1,97 @@
+package zstring
+
+import (
+	"testing"
+
+	"github.com/Drakmyth/golang-zmachine/testassert"
+)
+
+func TestUnicodeTable_ToRune(t *testing.T) {
+	type spec struct {
+		version  int
+		zc       ZSCII
+		expected rune
+	}
+
+	tests := map[string]spec{
+		"newline":              {version: 3, zc: 13, expected: '\n'},
+		"space":                {version: 3, zc: 32, expected: ' '},
+		"ascii":                {version: 3, zc: 'A', expected: 'A'},
+		"tilde":                {version: 3, zc: 126, expected: '~'},
+		"first extra":          {version: 5, zc: 155, expected: 'ä'},
+		"last default extra":   {version: 5, zc: 223, expected: '¿'},
+		"v6 tab":               {version: 6, zc: 9, expected: '\t'},
+		"v6 sentence space":    {version: 6, zc: 11, expected: ' '},
+		"pound sign":           {version: 5, zc: 219, expected: '£'},
+		"lowercase eszett":     {version: 5, zc: 161, expected: 'ß'},
+		"inverted exclamation": {version: 5, zc: 222, expected: '¡'},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			table := GetDefaultUnicodeTable(s.version)
+			actual, err := table.ToRune(s.zc)
+			testassert.NoError(t, err)
+			testassert.Same(t, s.expected, actual)
+		})
+	}
+}
+
+func TestUnicodeTable_ToRune_Undefined(t *testing.T) {
+	type spec struct {
+		version int
+		zc      ZSCII
+	}
+
+	tests := map[string]spec{
+		"null":                 {version: 3, zc: 0},
+		"v5 tab":               {version: 5, zc: 9},
+		"v5 sentence space":    {version: 5, zc: 11},
+		"delete":               {version: 3, zc: 127},
+		"function key":         {version: 5, zc: 133},
+		"beyond default extra": {version: 5, zc: 224},
+		"above extra range":    {version: 5, zc: 252},
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			table := GetDefaultUnicodeTable(s.version)
+			_, err := table.ToRune(s.zc)
+			testassert.True(t, err != nil)
+		})
+	}
+}
+
+func TestUnicodeTable_CustomTable(t *testing.T) {
+	table, err := NewUnicodeTable(5, []rune{'Ω', 'ł'})
+	testassert.NoError(t, err)
+
+	r, err := table.ToRune(155)
+	testassert.NoError(t, err)
+	testassert.Same(t, 'Ω', r)
+
+	r, err = table.ToRune(156)
+	testassert.NoError(t, err)
+	testassert.Same(t, 'ł', r)
+
+	_, err = table.ToRune(157)
+	testassert.ErrorMessage(t, "ZSCII character undefined for output", err)
+}
+
+func TestUnicodeTable_Translate(t *testing.T) {
+	table := GetDefaultUnicodeTable(5)
+
+	testassert.Same(t, "", table.Translate(0))
+	testassert.Same(t, "?", table.Translate(250))
+	testassert.Same(t, "é", table.Translate(170))
+	testassert.Same(t, "\n", table.Translate(13))
+}
+
+func TestZSCII_IsFunctionKey(t *testing.T) {
+	for _, zc := range []ZSCII{ZSCII_CursorUp, ZSCII_F1, ZSCII_Keypad0 + 9, ZSCII_MenuClick, ZSCII_DoubleClick, ZSCII_SingleClick} {
+		testassert.True(t, zc.IsFunctionKey())
+	}
+	for _, zc := range []ZSCII{ZSCII_NewLine, ZSCII_Escape, 'a', ZSCII_FirstExtra, ZSCII_LastExtra, 255} {
+		testassert.False(t, zc.IsFunctionKey())
+	}
+}